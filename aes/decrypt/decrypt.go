@@ -0,0 +1,108 @@
+package decrypt
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/aes/internal/aesgcm"
+)
+
+// Config represents the configuration used to create a new AES decryption
+// value modifier.
+type Config struct {
+	// Settings.
+
+	// Keys is the key set, mapping key IDs to 32 byte AES-256 keys. Values are
+	// decrypted using the key whose ID they are prefixed with, so that Keys
+	// should contain all keys values may still be encrypted with.
+	Keys map[string][]byte
+	// PathAAD authenticates the path of each traversed value as associated
+	// data. It must be provided when values were encrypted using
+	// config.PathAAD of the AES encryption value modifier. PathAAD requires the
+	// value modifier to be used via Traverse or Rotate.
+	PathAAD bool
+}
+
+// DefaultConfig provides a default configuration to create a new AES
+// decryption value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Keys:    nil,
+		PathAAD: false,
+	}
+}
+
+// New creates a new configured AES decryption value modifier.
+func New(config Config) (*Service, error) {
+	// Settings.
+	if len(config.Keys) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Keys must not be empty")
+	}
+
+	keys := map[string][]byte{}
+	for keyID, key := range config.Keys {
+		if !aesgcm.ValidKeyID(keyID) {
+			return nil, microerror.Maskf(invalidConfigError, "config.Keys must not contain key ID %q", keyID)
+		}
+		if len(key) != aesgcm.KeySize {
+			return nil, microerror.Maskf(invalidConfigError, "config.Keys must only contain %d byte keys, key %q has %d bytes", aesgcm.KeySize, keyID, len(key))
+		}
+		keys[keyID] = append([]byte(nil), key...)
+	}
+
+	newService := &Service{
+		keys:    keys,
+		pathAAD: config.PathAAD,
+	}
+
+	return newService, nil
+}
+
+// Service implements the AES decryption value modifier.
+type Service struct {
+	// Settings.
+	keys    map[string][]byte
+	pathAAD bool
+}
+
+// Modify decrypts values produced by the AES encryption value modifier.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if s.pathAAD {
+		return nil, microerror.Maskf(missingPathError, "value must be modified via ModifyPath when config.PathAAD provided")
+	}
+
+	return s.decrypt("", value)
+}
+
+// ModifyPath works like Modify, but authenticates the given path as associated
+// data when config.PathAAD is provided.
+func (s *Service) ModifyPath(path string, value []byte) ([]byte, error) {
+	if !s.pathAAD {
+		path = ""
+	}
+
+	return s.decrypt(path, value)
+}
+
+func (s *Service) decrypt(path string, value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	keyID, sealed, err := aesgcm.Split(value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, microerror.Maskf(keyNotFoundError, "config.Keys must contain key ID %q", keyID)
+	}
+
+	plainText, err := aesgcm.Open(key, keyID, path, sealed)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return plainText, nil
+}
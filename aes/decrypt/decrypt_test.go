@@ -0,0 +1,270 @@
+package decrypt
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/valuemodifier"
+	"github.com/giantswarm/valuemodifier/aes/encrypt"
+	"github.com/giantswarm/valuemodifier/path"
+)
+
+func Test_AES_Decrypt_Service_Modify(t *testing.T) {
+	k1 := bytes.Repeat([]byte{1}, 32)
+	k2 := bytes.Repeat([]byte{2}, 32)
+
+	testCases := []struct {
+		EncryptKeyID string
+		DecryptKeys  map[string][]byte
+		Value        func(value []byte) []byte
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, values are decrypted using the key they were encrypted
+		// with.
+		{
+			EncryptKeyID: "k1",
+			DecryptKeys:  map[string][]byte{"k1": k1, "k2": k2},
+		},
+		// Test case 2, values encrypted with a rotated key are decrypted.
+		{
+			EncryptKeyID: "k2",
+			DecryptKeys:  map[string][]byte{"k1": k1, "k2": k2},
+		},
+		// Test case 3, values encrypted with an unknown key are rejected.
+		{
+			EncryptKeyID: "k2",
+			DecryptKeys:  map[string][]byte{"k1": k1},
+			ErrorMatcher: IsKeyNotFound,
+		},
+		// Test case 4, values encrypted with another key of the same ID are
+		// rejected.
+		{
+			EncryptKeyID: "k1",
+			DecryptKeys:  map[string][]byte{"k1": k2},
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 5, values relabeled to another key ID are rejected.
+		{
+			EncryptKeyID: "k1",
+			DecryptKeys:  map[string][]byte{"k1": k1, "k2": k1},
+			Value: func(value []byte) []byte {
+				return []byte(strings.Replace(string(value), "aes:k1:", "aes:k2:", 1))
+			},
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 6, values which are not encrypted are rejected.
+		{
+			EncryptKeyID: "k1",
+			DecryptKeys:  map[string][]byte{"k1": k1},
+			Value: func(value []byte) []byte {
+				return []byte("hello world")
+			},
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+	}
+
+	for i, tc := range testCases {
+		var encryptService *encrypt.Service
+		{
+			config := encrypt.DefaultConfig()
+			config.Keys = map[string][]byte{"k1": k1, "k2": k2}
+			config.KeyID = tc.EncryptKeyID
+			var err error
+			encryptService, err = encrypt.New(config)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+		}
+
+		config := DefaultConfig()
+		config.Keys = tc.DecryptKeys
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		encrypted, err := encryptService.Modify([]byte("hello world"))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if tc.Value != nil {
+			encrypted = tc.Value(encrypted)
+		}
+
+		decrypted, err := newService.Modify(encrypted)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(decrypted) != "hello world" {
+			t.Fatal("test", i+1, "expected", "hello world", "got", string(decrypted))
+		}
+	}
+}
+
+func Test_AES_Decrypt_Traverse_PathAAD(t *testing.T) {
+	keys := map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}
+
+	var encryptTraverser *valuemodifier.Service
+	{
+		config := encrypt.DefaultConfig()
+		config.Keys = keys
+		config.KeyID = "k1"
+		config.PathAAD = true
+		encryptService, err := encrypt.New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{encryptService}
+		encryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	var decryptTraverser *valuemodifier.Service
+	{
+		config := DefaultConfig()
+		config.Keys = keys
+		config.PathAAD = true
+		decryptService, err := New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{decryptService}
+		decryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	input := `k1:
+  k2: v2
+k3:
+- v3
+- v4
+`
+	encrypted, err := encryptTraverser.Traverse([]byte(input))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if strings.Count(string(encrypted), "aes:k1:") != 3 {
+		t.Fatal("expected", 3, "got", string(encrypted))
+	}
+
+	decrypted, err := decryptTraverser.Traverse(encrypted)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(decrypted) != input {
+		t.Fatal("expected", fmt.Sprintf("%q", input), "got", fmt.Sprintf("%q", decrypted))
+	}
+
+	// Values moved to another path cannot be decrypted anymore, because their
+	// path is authenticated.
+	lines := strings.Split(string(encrypted), "\n")
+	moved := fmt.Sprintf("k1:\n  k2: %s\n", strings.TrimPrefix(lines[3], "- "))
+	_, err = decryptTraverser.Traverse([]byte(moved))
+	if !IsInvalidCiphertext(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func Test_AES_Decrypt_Diff_PathAAD(t *testing.T) {
+	keys := map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}
+
+	var encryptTraverser *valuemodifier.Service
+	{
+		config := encrypt.DefaultConfig()
+		config.Keys = keys
+		config.KeyID = "k1"
+		config.PathAAD = true
+		encryptService, err := encrypt.New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{encryptService}
+		encryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	var decryptService *Service
+	{
+		config := DefaultConfig()
+		config.Keys = keys
+		config.PathAAD = true
+		var err error
+		decryptService, err = New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	a, err := encryptTraverser.Traverse([]byte("k1:\n  k2: v2\nk3:\n- v3\n- v4\n"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	b, err := encryptTraverser.Traverse([]byte("k1:\n  k2: v2\nk3:\n- v3\n- modified\n"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Re-encrypted values bound to their path are only reported as changed
+	// when their plain text changed.
+	changes, err := path.Diff(a, b, decryptService)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	expected := []path.Change{
+		{Path: "k3.[1]", Type: path.ChangeTypeChanged},
+	}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Fatal("expected", expected, "got", changes)
+	}
+}
+
+func Test_AES_Decrypt_New_Error(t *testing.T) {
+	testCases := []struct {
+		Config Config
+	}{
+		// Test case 1, no keys are given.
+		{
+			Config: DefaultConfig(),
+		},
+		// Test case 2, keys must be AES-256 keys.
+		{
+			Config: Config{
+				Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 24)},
+			},
+		},
+		// Test case 3, key IDs must not be empty.
+		{
+			Config: Config{
+				Keys: map[string][]byte{"": bytes.Repeat([]byte{1}, 32)},
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := New(tc.Config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
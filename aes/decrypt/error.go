@@ -0,0 +1,42 @@
+package decrypt
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/aes/internal/aesgcm"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var keyNotFoundError = &microerror.Error{
+	Kind: "keyNotFoundError",
+}
+
+// IsKeyNotFound asserts keyNotFoundError, which is returned when values are
+// encrypted using a key ID missing in config.Keys.
+func IsKeyNotFound(err error) bool {
+	return microerror.Cause(err) == keyNotFoundError
+}
+
+var missingPathError = &microerror.Error{
+	Kind: "missingPathError",
+}
+
+// IsMissingPath asserts missingPathError.
+func IsMissingPath(err error) bool {
+	return microerror.Cause(err) == missingPathError
+}
+
+// IsInvalidCiphertext asserts that a value is not of the form
+// "aes:<key ID>:<cipher text>", or could not be authenticated, e.g. because it
+// was tampered with or decrypted at another path.
+func IsInvalidCiphertext(err error) bool {
+	return aesgcm.IsInvalidCiphertext(err)
+}
@@ -0,0 +1,106 @@
+package encrypt
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/aes/internal/aesgcm"
+)
+
+// Config represents the configuration used to create a new AES encryption
+// value modifier.
+type Config struct {
+	// Settings.
+
+	// Keys is the key set, mapping key IDs to 32 byte AES-256 keys. Key IDs
+	// must only consist of letters, digits, dots, underscores and dashes.
+	Keys map[string][]byte
+	// KeyID is the ID of the key in Keys used to encrypt values. Encrypted
+	// values are prefixed with it, so that they can still be decrypted once
+	// KeyID is changed to rotate keys.
+	KeyID string
+	// PathAAD authenticates the path of each traversed value as associated
+	// data, which binds encrypted values to their location within a document.
+	// Values can then only be decrypted at the very same path. PathAAD
+	// requires the value modifier to be used via Traverse or Rotate.
+	PathAAD bool
+}
+
+// DefaultConfig provides a default configuration to create a new AES
+// encryption value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Keys:    nil,
+		KeyID:   "",
+		PathAAD: false,
+	}
+}
+
+// New creates a new configured AES encryption value modifier.
+func New(config Config) (*Service, error) {
+	// Settings.
+	for keyID, key := range config.Keys {
+		if !aesgcm.ValidKeyID(keyID) {
+			return nil, microerror.Maskf(invalidConfigError, "config.Keys must not contain key ID %q", keyID)
+		}
+		if len(key) != aesgcm.KeySize {
+			return nil, microerror.Maskf(invalidConfigError, "config.Keys must only contain %d byte keys, key %q has %d bytes", aesgcm.KeySize, keyID, len(key))
+		}
+	}
+	if config.KeyID == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.KeyID must not be empty")
+	}
+	key, ok := config.Keys[config.KeyID]
+	if !ok {
+		return nil, microerror.Maskf(invalidConfigError, "config.Keys must contain config.KeyID %q", config.KeyID)
+	}
+
+	newService := &Service{
+		key:     append([]byte(nil), key...),
+		keyID:   config.KeyID,
+		pathAAD: config.PathAAD,
+	}
+
+	return newService, nil
+}
+
+// Service implements the AES encryption value modifier.
+type Service struct {
+	// Settings.
+	key     []byte
+	keyID   string
+	pathAAD bool
+}
+
+// Modify encrypts the given value using AES-256-GCM and returns it in the form
+// "aes:<key ID>:<base64 nonce and cipher text>".
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if s.pathAAD {
+		return nil, microerror.Maskf(missingPathError, "value must be modified via ModifyPath when config.PathAAD provided")
+	}
+
+	return s.encrypt("", value)
+}
+
+// ModifyPath works like Modify, but authenticates the given path as associated
+// data when config.PathAAD is provided.
+func (s *Service) ModifyPath(path string, value []byte) ([]byte, error) {
+	if !s.pathAAD {
+		path = ""
+	}
+
+	return s.encrypt(path, value)
+}
+
+func (s *Service) encrypt(path string, value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	sealed, err := aesgcm.Seal(s.key, s.keyID, path, value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return sealed, nil
+}
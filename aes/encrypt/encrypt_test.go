@@ -0,0 +1,110 @@
+package encrypt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_AES_Encrypt_Service_Modify(t *testing.T) {
+	config := DefaultConfig()
+	config.Keys = map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}
+	config.KeyID = "k2"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	first, err := newService.Modify([]byte("hello world"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(first), "aes:k2:") {
+		t.Fatal("expected", "aes:k2:", "got", string(first))
+	}
+
+	// Every value is encrypted using a random nonce.
+	second, err := newService.Modify([]byte("hello world"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(first) == string(second) {
+		t.Fatal("expected", "different cipher texts", "got", string(second))
+	}
+
+	empty, err := newService.Modify([]byte(""))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(empty) != "" {
+		t.Fatal("expected", "", "got", string(empty))
+	}
+}
+
+func Test_AES_Encrypt_Service_Modify_PathAAD(t *testing.T) {
+	config := DefaultConfig()
+	config.Keys = map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+	}
+	config.KeyID = "k1"
+	config.PathAAD = true
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	_, err = newService.Modify([]byte("hello world"))
+	if !IsMissingPath(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+
+	modified, err := newService.ModifyPath("k1.k2", []byte("hello world"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(modified), "aes:k1:") {
+		t.Fatal("expected", "aes:k1:", "got", string(modified))
+	}
+}
+
+func Test_AES_Encrypt_New_Error(t *testing.T) {
+	testCases := []struct {
+		Config Config
+	}{
+		// Test case 1, neither keys nor a key ID are given.
+		{
+			Config: DefaultConfig(),
+		},
+		// Test case 2, the key ID is missing in the key set.
+		{
+			Config: Config{
+				Keys:  map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)},
+				KeyID: "k2",
+			},
+		},
+		// Test case 3, keys must be AES-256 keys.
+		{
+			Config: Config{
+				Keys:  map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)},
+				KeyID: "k1",
+			},
+		},
+		// Test case 4, key IDs must not contain colons.
+		{
+			Config: Config{
+				Keys:  map[string][]byte{"k:1": bytes.Repeat([]byte{1}, 32)},
+				KeyID: "k:1",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := New(tc.Config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
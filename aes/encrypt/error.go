@@ -0,0 +1,21 @@
+package encrypt
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var missingPathError = &microerror.Error{
+	Kind: "missingPathError",
+}
+
+// IsMissingPath asserts missingPathError.
+func IsMissingPath(err error) bool {
+	return microerror.Cause(err) == missingPathError
+}
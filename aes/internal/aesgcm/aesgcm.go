@@ -0,0 +1,121 @@
+// Package aesgcm implements the format of values encrypted locally using
+// AES-256-GCM with a key selected by its ID.
+package aesgcm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"regexp"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Prefix is the prefix of all values encrypted using AES-256-GCM.
+const Prefix = "aes:"
+
+// KeySize is the size of AES-256 keys in bytes.
+const KeySize = 32
+
+var keyIDExpression = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidKeyID returns whether the given key ID can be used within encrypted
+// values. Key IDs must only consist of letters, digits, dots, underscores and
+// dashes.
+func ValidKeyID(keyID string) bool {
+	return keyIDExpression.MatchString(keyID)
+}
+
+// Seal encrypts the given plain text with the given key using AES-256-GCM and
+// returns a value of the form "aes:<key ID>:<base64 nonce and cipher text>".
+// The key ID and the given path are authenticated as associated data. The
+// path is empty when values are not bound to their location.
+func Seal(key []byte, keyID string, path string, plainText []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plainText, additionalData(keyID, path))
+
+	var b strings.Builder
+	b.WriteString(Prefix)
+	b.WriteString(keyID)
+	b.WriteString(":")
+	b.WriteString(base64.StdEncoding.EncodeToString(sealed))
+
+	return []byte(b.String()), nil
+}
+
+// Split returns the key ID and the sealed cipher text of the given value.
+func Split(value []byte) (string, []byte, error) {
+	s := string(value)
+	if !strings.HasPrefix(s, Prefix) {
+		return "", nil, microerror.Maskf(invalidCiphertextError, "value must start with %q", Prefix)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(s, Prefix), ":")
+	if len(parts) != 2 || !ValidKeyID(parts[0]) {
+		return "", nil, microerror.Maskf(invalidCiphertextError, "value must be of the form %s<key ID>:<cipher text>", Prefix)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, microerror.Maskf(invalidCiphertextError, "cipher text must be base64 encoded")
+	}
+
+	return parts[0], sealed, nil
+}
+
+// Open decrypts the given sealed cipher text, as returned by Split, with the
+// given key. The key ID and path must match the ones the value was sealed
+// with.
+func Open(key []byte, keyID string, path string, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, microerror.Maskf(invalidCiphertextError, "cipher text must contain a nonce")
+	}
+
+	nonce, cipherText := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plainText, err := aead.Open(nil, nonce, cipherText, additionalData(keyID, path))
+	if err != nil {
+		return nil, microerror.Maskf(invalidCiphertextError, "%s", err.Error())
+	}
+
+	return plainText, nil
+}
+
+// additionalData binds cipher texts to their key ID and path. Key IDs cannot
+// contain colons, so that the encoding is unambiguous.
+func additionalData(keyID string, path string) []byte {
+	return []byte(Prefix + keyID + ":" + path)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, microerror.Maskf(invalidKeyError, "key must be %d bytes long", KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return aead, nil
+}
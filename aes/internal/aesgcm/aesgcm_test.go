@@ -0,0 +1,86 @@
+package aesgcm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_AESGCM_Seal_Open(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, KeySize)
+	expected := []byte("hello world")
+
+	sealed, err := Seal(key, "2026-01", "k1.k2", expected)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(sealed), "aes:2026-01:") {
+		t.Fatal("expected", "aes:2026-01:", "got", string(sealed))
+	}
+
+	keyID, c, err := Split(sealed)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if keyID != "2026-01" {
+		t.Fatal("expected", "2026-01", "got", keyID)
+	}
+
+	plainText, err := Open(key, keyID, "k1.k2", c)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(plainText) != string(expected) {
+		t.Fatal("expected", string(expected), "got", string(plainText))
+	}
+
+	// The key ID and path are authenticated, so that cipher texts can neither
+	// be relabeled nor moved to other paths.
+	_, err = Open(key, "2026-02", "k1.k2", c)
+	if !IsInvalidCiphertext(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+	_, err = Open(key, keyID, "k1.k3", c)
+	if !IsInvalidCiphertext(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+
+	_, err = Seal([]byte("short"), keyID, "", expected)
+	if !IsInvalidKey(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func Test_AESGCM_Split_Error(t *testing.T) {
+	testCases := []struct {
+		Value string
+	}{
+		// Test case 1, values without prefix are rejected.
+		{
+			Value: "foo",
+		},
+		// Test case 2, values without key ID are rejected.
+		{
+			Value: "aes::Zm9v",
+		},
+		// Test case 3, values with invalid key IDs are rejected.
+		{
+			Value: "aes:k 1:Zm9v",
+		},
+		// Test case 4, values with too many parts are rejected.
+		{
+			Value: "aes:k1:Zm9v:Zm9v",
+		},
+		// Test case 5, values which are not base64 encoded are rejected.
+		{
+			Value: "aes:k1:%%%",
+		},
+	}
+
+	for i, tc := range testCases {
+		_, _, err := Split([]byte(tc.Value))
+		if !IsInvalidCiphertext(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
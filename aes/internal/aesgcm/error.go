@@ -0,0 +1,21 @@
+package aesgcm
+
+import "github.com/giantswarm/microerror"
+
+var invalidCiphertextError = &microerror.Error{
+	Kind: "invalidCiphertextError",
+}
+
+// IsInvalidCiphertext asserts invalidCiphertextError.
+func IsInvalidCiphertext(err error) bool {
+	return microerror.Cause(err) == invalidCiphertextError
+}
+
+var invalidKeyError = &microerror.Error{
+	Kind: "invalidKeyError",
+}
+
+// IsInvalidKey asserts invalidKeyError.
+func IsInvalidKey(err error) bool {
+	return microerror.Cause(err) == invalidKeyError
+}
@@ -0,0 +1,196 @@
+package decrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/giantswarm/microerror"
+	"golang.org/x/crypto/ssh"
+)
+
+// binaryPrefix is the version line every binary age file starts with.
+const binaryPrefix = "age-encryption.org/"
+
+// Config represents the configuration used to create a new age decryption
+// value modifier.
+type Config struct {
+	// Settings.
+
+	// Pass is the passphrase used to decrypt age files encrypted using
+	// scrypt. When identities are configured as well, Pass is used for
+	// passphrase encrypted age files only.
+	Pass string
+	// MaxWorkFactor is the maximum scrypt work factor accepted for age files
+	// encrypted using Pass, given as the base 2 logarithm of the number of
+	// iterations. It must be between 1 and 29. When empty the library default
+	// is used.
+	MaxWorkFactor int
+	// Identities are the identities used to decrypt age files. Each item is
+	// either the content of an age identity file, containing native X25519 or
+	// hybrid post-quantum identities one per line, or a PEM encoded
+	// "ssh-ed25519" or "ssh-rsa" SSH private key.
+	Identities []string
+	// IdentityFiles are paths of files parsed like Identities.
+	IdentityFiles []string
+	// KeyPass is the passphrase used to unlock passphrase protected SSH
+	// private keys of Identities and IdentityFiles.
+	KeyPass string
+}
+
+// DefaultConfig provides a default configuration to create a new age
+// decryption value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Pass:          "",
+		MaxWorkFactor: 0,
+		Identities:    nil,
+		IdentityFiles: nil,
+		KeyPass:       "",
+	}
+}
+
+// New creates a new configured age decryption value modifier.
+func New(config Config) (*Service, error) {
+	var identities []age.Identity
+	{
+		for _, i := range config.Identities {
+			parsed, err := parseIdentities([]byte(i), config.KeyPass)
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.Identities must only contain age or SSH identities: %s", err.Error())
+			}
+			identities = append(identities, parsed...)
+		}
+
+		for _, f := range config.IdentityFiles {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.IdentityFiles must only contain readable files: %s", err.Error())
+			}
+			parsed, err := parseIdentities(b, config.KeyPass)
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.IdentityFiles must only contain age or SSH identities: %s: %s", f, err.Error())
+			}
+			identities = append(identities, parsed...)
+		}
+	}
+
+	// Settings.
+	if config.MaxWorkFactor < 0 || config.MaxWorkFactor >= 30 {
+		return nil, microerror.Maskf(invalidConfigError, "config.MaxWorkFactor must be between 1 and 29")
+	}
+	if config.Pass != "" {
+		i, err := age.NewScryptIdentity(config.Pass)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.Pass must be a valid passphrase: %s", err.Error())
+		}
+		if config.MaxWorkFactor != 0 {
+			i.SetMaxWorkFactor(config.MaxWorkFactor)
+		}
+		identities = append(identities, i)
+	}
+	if len(identities) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pass or config.Identities or config.IdentityFiles must not be empty")
+	}
+
+	newService := &Service{
+		identities: identities,
+	}
+
+	return newService, nil
+}
+
+// Service implements the age decryption value modifier.
+type Service struct {
+	// Settings.
+	identities []age.Identity
+}
+
+// Modify decrypts the given age file. Armored, compact base64 encoded and
+// binary age files are detected automatically.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	in, err := readFile(value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	decrypter, err := age.Decrypt(in, s.identities...)
+	if err != nil {
+		var noIdentityMatch *age.NoIdentityMatchError
+		if errors.As(err, &noIdentityMatch) {
+			return nil, microerror.Maskf(noMatchingIdentityError, "%s", err.Error())
+		}
+		return nil, microerror.Mask(err)
+	}
+
+	decrypted, err := io.ReadAll(decrypter)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return decrypted, nil
+}
+
+// readFile returns a reader of the binary age file the given value holds,
+// which is either armored, compact base64 encoded or binary.
+func readFile(value []byte) (io.Reader, error) {
+	trimmed := bytes.TrimSpace(value)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte(armor.Header)):
+		return armor.NewReader(bytes.NewReader(trimmed)), nil
+	case bytes.HasPrefix(value, []byte(binaryPrefix)):
+		return bytes.NewReader(value), nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(string(trimmed))
+	if err != nil || !bytes.HasPrefix(b, []byte(binaryPrefix)) {
+		return nil, microerror.Maskf(invalidFormatError, "value must be an armored, compact or binary age file")
+	}
+
+	return bytes.NewReader(b), nil
+}
+
+// parseIdentities parses either an age identity file or a PEM encoded SSH
+// private key.
+func parseIdentities(b []byte, keyPass string) ([]age.Identity, error) {
+	if !strings.Contains(string(b), "-----BEGIN") {
+		identities, err := age.ParseIdentities(bytes.NewReader(b))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return identities, nil
+	}
+
+	identity, err := agessh.ParseIdentity(b)
+	var passphraseMissing *ssh.PassphraseMissingError
+	if errors.As(err, &passphraseMissing) {
+		if keyPass == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.KeyPass must not be empty when SSH private keys are passphrase protected")
+		}
+		if passphraseMissing.PublicKey == nil {
+			return nil, microerror.Maskf(invalidConfigError, "passphrase protected SSH private keys must contain their public key")
+		}
+
+		identity, err = agessh.NewEncryptedSSHIdentity(passphraseMissing.PublicKey, b, func() ([]byte, error) {
+			return []byte(keyPass), nil
+		})
+	}
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return []age.Identity{identity}, nil
+}
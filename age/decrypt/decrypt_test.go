@@ -0,0 +1,275 @@
+package decrypt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/giantswarm/valuemodifier/age/encrypt"
+)
+
+func Test_AGE_Decrypt_Service_Modify(t *testing.T) {
+	alice, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	bob, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	sshKey, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	sshProtectedKey, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("bar"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	identityFile := filepath.Join(t.TempDir(), "identity.txt")
+	err = os.WriteFile(identityFile, []byte("# alice\n"+alice.String()+"\n"), 0600)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	testCases := []struct {
+		EncryptConfig encrypt.Config
+		DecryptConfig Config
+		ErrorMatcher  func(error) bool
+	}{
+		// Test case 1, armored values encrypted to an X25519 recipient are
+		// decrypted.
+		{
+			EncryptConfig: encrypt.Config{
+				Recipients: []string{alice.Recipient().String()},
+			},
+			DecryptConfig: Config{
+				Identities: []string{alice.String()},
+			},
+		},
+		// Test case 2, binary values encrypted to several recipients are
+		// decrypted using an identity file.
+		{
+			EncryptConfig: encrypt.Config{
+				Recipients: []string{bob.Recipient().String(), alice.Recipient().String()},
+				Output:     encrypt.OutputBinary,
+			},
+			DecryptConfig: Config{
+				IdentityFiles: []string{identityFile},
+			},
+		},
+		// Test case 3, compact values encrypted to an SSH recipient are
+		// decrypted.
+		{
+			EncryptConfig: encrypt.Config{
+				Recipients: []string{string(ssh.MarshalAuthorizedKey(sshPub))},
+				Output:     encrypt.OutputCompact,
+			},
+			DecryptConfig: Config{
+				Identities: []string{string(pem.EncodeToMemory(sshKey))},
+			},
+		},
+		// Test case 4, values encrypted to an SSH recipient are decrypted
+		// using a passphrase protected SSH private key.
+		{
+			EncryptConfig: encrypt.Config{
+				Recipients: []string{string(ssh.MarshalAuthorizedKey(sshPub))},
+			},
+			DecryptConfig: Config{
+				Identities: []string{string(pem.EncodeToMemory(sshProtectedKey))},
+				KeyPass:    "bar",
+			},
+		},
+		// Test case 5, values encrypted using a passphrase are decrypted,
+		// even when identities are configured as well.
+		{
+			EncryptConfig: encrypt.Config{
+				Pass:       "foo",
+				WorkFactor: 10,
+			},
+			DecryptConfig: Config{
+				Pass:       "foo",
+				Identities: []string{alice.String()},
+			},
+		},
+		// Test case 6, values encrypted to other recipients are rejected.
+		{
+			EncryptConfig: encrypt.Config{
+				Recipients: []string{bob.Recipient().String()},
+			},
+			DecryptConfig: Config{
+				Identities: []string{alice.String()},
+			},
+			ErrorMatcher: IsNoMatchingIdentity,
+		},
+		// Test case 7, values encrypted using another passphrase are
+		// rejected.
+		{
+			EncryptConfig: encrypt.Config{
+				Pass:       "foo",
+				WorkFactor: 10,
+			},
+			DecryptConfig: Config{
+				Pass: "bar",
+			},
+			ErrorMatcher: IsNoMatchingIdentity,
+		},
+		// Test case 8, values encrypted using a higher work factor than
+		// accepted are rejected.
+		{
+			EncryptConfig: encrypt.Config{
+				Pass:       "foo",
+				WorkFactor: 12,
+			},
+			DecryptConfig: Config{
+				Pass:          "foo",
+				MaxWorkFactor: 10,
+			},
+			ErrorMatcher: func(err error) bool { return err != nil },
+		},
+	}
+
+	for i, tc := range testCases {
+		encryptService, err := encrypt.New(tc.EncryptConfig)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		decryptService, err := New(tc.DecryptConfig)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		encrypted, err := encryptService.Modify([]byte("hello world"))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		decrypted, err := decryptService.Modify(encrypted)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(decrypted) != "hello world" {
+			t.Fatal("test", i+1, "expected", "hello world", "got", string(decrypted))
+		}
+	}
+}
+
+func Test_AGE_Decrypt_Service_Modify_Error(t *testing.T) {
+	config := DefaultConfig()
+	config.Pass = "foo"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	testCases := []struct {
+		Value string
+	}{
+		// Test case 1, plain text is rejected.
+		{
+			Value: "hello world",
+		},
+		// Test case 2, base64 encoded data which is no age file is rejected.
+		{
+			Value: "aGVsbG8gd29ybGQ=",
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := newService.Modify([]byte(tc.Value))
+		if !IsInvalidFormat(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
+
+func Test_AGE_Decrypt_Service_Modify_Empty(t *testing.T) {
+	config := DefaultConfig()
+	config.Pass = "foo"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	modified, err := newService.Modify([]byte(""))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(modified) != "" {
+		t.Fatal("expected", "", "got", string(modified))
+	}
+}
+
+func Test_AGE_Decrypt_New_Error(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	sshProtectedKey, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("bar"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	testCases := []struct {
+		Config Config
+	}{
+		// Test case 1, neither a passphrase nor identities are given.
+		{
+			Config: DefaultConfig(),
+		},
+		// Test case 2, identities are invalid.
+		{
+			Config: Config{
+				Identities: []string{"foo"},
+			},
+		},
+		// Test case 3, identity files do not exist.
+		{
+			Config: Config{
+				IdentityFiles: []string{filepath.Join(t.TempDir(), "missing.txt")},
+			},
+		},
+		// Test case 4, passphrase protected SSH private keys require a key
+		// passphrase.
+		{
+			Config: Config{
+				Identities: []string{string(pem.EncodeToMemory(sshProtectedKey))},
+			},
+		},
+		// Test case 5, the maximum work factor is out of range.
+		{
+			Config: Config{
+				Pass:          "foo",
+				MaxWorkFactor: 30,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := New(tc.Config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
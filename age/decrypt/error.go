@@ -0,0 +1,31 @@
+package decrypt
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidFormatError = &microerror.Error{
+	Kind: "invalidFormatError",
+}
+
+// IsInvalidFormat asserts invalidFormatError.
+func IsInvalidFormat(err error) bool {
+	return microerror.Cause(err) == invalidFormatError
+}
+
+var noMatchingIdentityError = &microerror.Error{
+	Kind: "noMatchingIdentityError",
+}
+
+// IsNoMatchingIdentity asserts noMatchingIdentityError, which is returned
+// when neither the configured identities nor the passphrase decrypt a value.
+func IsNoMatchingIdentity(err error) bool {
+	return microerror.Cause(err) == noMatchingIdentityError
+}
@@ -0,0 +1,225 @@
+package encrypt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// OutputArmored produces PEM like armored age files spanning multiple
+	// lines.
+	OutputArmored = "armored"
+	// OutputBinary produces raw binary age files, e.g. to be encoded by the
+	// base64 encoding value modifier afterwards.
+	OutputBinary = "binary"
+	// OutputCompact produces base64 encoded binary age files on a single line,
+	// without any armor.
+	OutputCompact = "compact"
+)
+
+// Config represents the configuration used to create a new age encryption
+// value modifier.
+type Config struct {
+	// Settings.
+
+	// Pass is the passphrase used to encrypt age files using scrypt. Pass must
+	// not be used together with Recipients or RecipientFiles.
+	Pass string
+	// WorkFactor is the scrypt work factor used with Pass, given as the base 2
+	// logarithm of the number of iterations. It must be between 1 and 29. When
+	// empty the library default is used.
+	WorkFactor int
+	// Recipients are the recipients age files are encrypted to. Each item may
+	// contain multiple recipients, one per line, in the format of age
+	// recipients files. Native X25519 and hybrid post-quantum recipients as
+	// well as "ssh-ed25519" and "ssh-rsa" SSH public keys are supported.
+	// Empty lines and lines starting with "#" are ignored.
+	Recipients []string
+	// RecipientFiles are paths of age recipients files. They are parsed like
+	// Recipients.
+	RecipientFiles []string
+
+	// Output is the format age files are returned in. It is one of
+	// OutputArmored, OutputBinary or OutputCompact.
+	Output string
+}
+
+// DefaultConfig provides a default configuration to create a new age
+// encryption value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Pass:           "",
+		WorkFactor:     0,
+		Recipients:     nil,
+		RecipientFiles: nil,
+
+		Output: OutputArmored,
+	}
+}
+
+// New creates a new configured age encryption value modifier.
+func New(config Config) (*Service, error) {
+	var recipients []age.Recipient
+	{
+		for _, r := range config.Recipients {
+			parsed, err := parseRecipients(r)
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.Recipients must only contain age or SSH recipients: %s", err.Error())
+			}
+			recipients = append(recipients, parsed...)
+		}
+
+		for _, f := range config.RecipientFiles {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.RecipientFiles must only contain readable files: %s", err.Error())
+			}
+			parsed, err := parseRecipients(string(b))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.RecipientFiles must only contain age or SSH recipients: %s: %s", f, err.Error())
+			}
+			recipients = append(recipients, parsed...)
+		}
+	}
+
+	// Settings.
+	if config.Pass == "" && len(recipients) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pass or config.Recipients or config.RecipientFiles must not be empty")
+	}
+	if config.Pass != "" && len(recipients) != 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pass must be empty when config.Recipients or config.RecipientFiles provided")
+	}
+
+	if config.Pass != "" {
+		if config.WorkFactor < 0 || config.WorkFactor >= 30 {
+			return nil, microerror.Maskf(invalidConfigError, "config.WorkFactor must be between 1 and 29")
+		}
+
+		r, err := age.NewScryptRecipient(config.Pass)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.Pass must be a valid passphrase: %s", err.Error())
+		}
+		if config.WorkFactor != 0 {
+			r.SetWorkFactor(config.WorkFactor)
+		}
+		recipients = append(recipients, r)
+	} else if config.WorkFactor != 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.WorkFactor must be empty when config.Pass is empty")
+	}
+
+	output := config.Output
+	switch output {
+	case "":
+		// An empty output falls back to OutputArmored to keep configurations
+		// working which do not use DefaultConfig.
+		output = OutputArmored
+	case OutputArmored, OutputBinary, OutputCompact:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Output must be one of %q, %q or %q", OutputArmored, OutputBinary, OutputCompact)
+	}
+
+	newService := &Service{
+		output:     output,
+		recipients: recipients,
+	}
+
+	return newService, nil
+}
+
+// Service implements the age encryption value modifier.
+type Service struct {
+	// Settings.
+	output     string
+	recipients []age.Recipient
+}
+
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	var out io.Writer = buf
+
+	var encoder io.WriteCloser
+	if s.output == OutputArmored {
+		encoder = armor.NewWriter(buf)
+		out = encoder
+	}
+
+	encrypter, err := age.Encrypt(out, s.recipients...)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	_, err = encrypter.Write(value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = encrypter.Close()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if encoder != nil {
+		err = encoder.Close()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if s.output == OutputCompact {
+		return []byte(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseRecipients parses recipients in the format of age recipients files,
+// additionally accepting SSH public keys.
+func parseRecipients(s string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var parsed []age.Recipient
+		if strings.HasPrefix(line, "ssh-") {
+			r, err := agessh.ParseRecipient(line)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			parsed = []age.Recipient{r}
+		} else {
+			var err error
+			parsed, err = age.ParseRecipients(strings.NewReader(line))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		recipients = append(recipients, parsed...)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return recipients, nil
+}
@@ -0,0 +1,216 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/ssh"
+)
+
+func Test_AGE_Encrypt_Service_Modify(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	testCases := []struct {
+		Output   string
+		Validate func(modified []byte) bool
+	}{
+		// Test case 1, armored age files are produced by default.
+		{
+			Output: "",
+			Validate: func(modified []byte) bool {
+				return strings.HasPrefix(string(modified), "-----BEGIN AGE ENCRYPTED FILE-----\n") &&
+					strings.HasSuffix(string(modified), "\n-----END AGE ENCRYPTED FILE-----\n")
+			},
+		},
+		// Test case 2, binary age files are produced.
+		{
+			Output: OutputBinary,
+			Validate: func(modified []byte) bool {
+				return bytes.HasPrefix(modified, []byte("age-encryption.org/v1\n"))
+			},
+		},
+		// Test case 3, compact age files are single line base64 encoded binary
+		// age files.
+		{
+			Output: OutputCompact,
+			Validate: func(modified []byte) bool {
+				b, err := base64.StdEncoding.DecodeString(string(modified))
+				return err == nil && bytes.HasPrefix(b, []byte("age-encryption.org/v1\n"))
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.Recipients = []string{identity.Recipient().String()}
+		config.Output = tc.Output
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := newService.Modify([]byte("hello world"))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !tc.Validate(modified) {
+			t.Fatal("test", i+1, "expected", true, "got", string(modified))
+		}
+	}
+}
+
+func Test_AGE_Encrypt_Service_Modify_Empty(t *testing.T) {
+	config := DefaultConfig()
+	config.Pass = "foo"
+	config.WorkFactor = 10
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	modified, err := newService.Modify([]byte(""))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(modified) != "" {
+		t.Fatal("expected", "", "got", string(modified))
+	}
+}
+
+func Test_AGE_Encrypt_New(t *testing.T) {
+	alice, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	bob, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	recipientFile := filepath.Join(t.TempDir(), "recipients.txt")
+	err = os.WriteFile(recipientFile, []byte("# bob\n"+bob.Recipient().String()+"\n"), 0600)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	testCases := []struct {
+		Config     Config
+		Recipients int
+	}{
+		// Test case 1, several recipients are parsed from a single item,
+		// ignoring comments and empty lines.
+		{
+			Config: Config{
+				Recipients: []string{"# alice\n" + alice.Recipient().String() + "\n\n" + string(ssh.MarshalAuthorizedKey(sshPub))},
+			},
+			Recipients: 2,
+		},
+		// Test case 2, recipients are read from files.
+		{
+			Config: Config{
+				Recipients:     []string{alice.Recipient().String()},
+				RecipientFiles: []string{recipientFile},
+			},
+			Recipients: 2,
+		},
+		// Test case 3, a passphrase is used.
+		{
+			Config: Config{
+				Pass:       "foo",
+				WorkFactor: 10,
+			},
+			Recipients: 1,
+		},
+	}
+
+	for i, tc := range testCases {
+		newService, err := New(tc.Config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if len(newService.recipients) != tc.Recipients {
+			t.Fatal("test", i+1, "expected", tc.Recipients, "got", len(newService.recipients))
+		}
+	}
+}
+
+func Test_AGE_Encrypt_New_Error(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	testCases := []struct {
+		Config Config
+	}{
+		// Test case 1, neither a passphrase nor recipients are given.
+		{
+			Config: DefaultConfig(),
+		},
+		// Test case 2, a passphrase and recipients are given.
+		{
+			Config: Config{
+				Pass:       "foo",
+				Recipients: []string{identity.Recipient().String()},
+			},
+		},
+		// Test case 3, recipients are invalid.
+		{
+			Config: Config{
+				Recipients: []string{"foo"},
+			},
+		},
+		// Test case 4, recipient files do not exist.
+		{
+			Config: Config{
+				RecipientFiles: []string{filepath.Join(t.TempDir(), "missing.txt")},
+			},
+		},
+		// Test case 5, the work factor is out of range.
+		{
+			Config: Config{
+				Pass:       "foo",
+				WorkFactor: 30,
+			},
+		},
+		// Test case 6, the work factor requires a passphrase.
+		{
+			Config: Config{
+				Recipients: []string{identity.Recipient().String()},
+				WorkFactor: 10,
+			},
+		},
+		// Test case 7, the output is unknown.
+		{
+			Config: Config{
+				Pass:   "foo",
+				Output: "foo",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := New(tc.Config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
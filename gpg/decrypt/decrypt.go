@@ -2,11 +2,27 @@ package decrypt
 
 import (
 	"bytes"
+	"encoding/base64"
 	"io"
+	"strings"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgarmor"
+)
+
+const (
+	// ProfileRFC4880 accepts classic OpenPGP messages as defined by RFC 4880 as
+	// well as OpenPGP messages as defined by RFC 9580.
+	ProfileRFC4880 = "rfc4880"
+	// ProfileRFC9580 only accepts OpenPGP messages as defined by RFC 9580,
+	// which use AEAD encrypted data packets, also known as SEIPD version 2.
+	// This can be used to enforce that all values were migrated.
+	ProfileRFC9580 = "rfc9580"
 )
 
 // Config represents the configuration used to create a new GPG decryption
@@ -14,8 +30,34 @@ import (
 type Config struct {
 	// Settings.
 
-	// Pass is the passphrase used to decrypt GPG messages.
+	// Pass is the passphrase used to decrypt symmetrically encrypted GPG
+	// messages. When private keys are configured as well, Pass is used as
+	// fallback for messages which are not encrypted to any of the keys.
 	Pass string
+	// Passes are further passphrases used to decrypt symmetrically encrypted
+	// GPG messages. They are tried in order after Pass, e.g. to decrypt values
+	// still encrypted using a previous passphrase while rotating passphrases.
+	Passes []string
+	// PrivateKeys are the armored private keys used to decrypt GPG messages
+	// encrypted to their public keys. Each item may contain multiple keys.
+	PrivateKeys []string
+	// KeyRing holds private keys used to decrypt GPG messages, in addition to
+	// the ones defined by PrivateKeys.
+	KeyRing openpgp.EntityList
+	// KeyPass is the passphrase used to unlock passphrase protected private
+	// keys of PrivateKeys and KeyRing.
+	KeyPass string
+	// SignerPublicKeys are the armored public keys of trusted signers. When
+	// SignerPublicKeys or SignerKeyRing are provided, every GPG message must be
+	// signed by one of the trusted signers. Each item may contain multiple
+	// keys.
+	SignerPublicKeys []string
+	// SignerKeyRing holds trusted signers, in addition to the ones defined by
+	// SignerPublicKeys.
+	SignerKeyRing openpgp.EntityList
+	// Profile is the OpenPGP profile GPG messages must comply with. It is
+	// either ProfileRFC4880 or ProfileRFC9580.
+	Profile string
 }
 
 // DefaultConfig provides a default configuration to create a new GPG decryption
@@ -23,19 +65,92 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Settings.
-		Pass: "",
+		Pass:             "",
+		Passes:           nil,
+		PrivateKeys:      nil,
+		KeyRing:          nil,
+		KeyPass:          "",
+		SignerPublicKeys: nil,
+		SignerKeyRing:    nil,
+		Profile:          ProfileRFC4880,
 	}
 }
 
 // New creates a new configured GPG decryption value modifier.
 func New(config Config) (*Service, error) {
+	var keyRing openpgp.EntityList
+	{
+		keyRing = append(keyRing, config.KeyRing...)
+
+		for _, k := range config.PrivateKeys {
+			entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(k))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.PrivateKeys must only contain armored private keys: %s", err.Error())
+			}
+			keyRing = append(keyRing, entities...)
+		}
+
+		for _, e := range keyRing {
+			if e.PrivateKey == nil {
+				return nil, microerror.Maskf(invalidConfigError, "key %X must be a private key", e.PrimaryKey.Fingerprint)
+			}
+			if !isLocked(e) {
+				continue
+			}
+			if config.KeyPass == "" {
+				return nil, microerror.Maskf(invalidConfigError, "config.KeyPass must not be empty when private key %X is passphrase protected", e.PrimaryKey.Fingerprint)
+			}
+
+			err := e.DecryptPrivateKeys([]byte(config.KeyPass))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.KeyPass must unlock private key %X: %s", e.PrimaryKey.Fingerprint, err.Error())
+			}
+		}
+	}
+
+	var signers openpgp.EntityList
+	{
+		signers = append(signers, config.SignerKeyRing...)
+
+		for _, k := range config.SignerPublicKeys {
+			entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(k))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.SignerPublicKeys must only contain armored public keys: %s", err.Error())
+			}
+			signers = append(signers, entities...)
+		}
+	}
+
+	var passes []string
+	{
+		if config.Pass != "" {
+			passes = append(passes, config.Pass)
+		}
+		for _, p := range config.Passes {
+			if p == "" {
+				return nil, microerror.Maskf(invalidConfigError, "config.Passes must not contain empty passphrases")
+			}
+			passes = append(passes, p)
+		}
+	}
+
 	// Settings.
-	if config.Pass == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.Pass must not be empty")
+	if len(passes) == 0 && len(keyRing) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pass or config.Passes or config.PrivateKeys or config.KeyRing must not be empty")
+	}
+	switch config.Profile {
+	case "", ProfileRFC4880, ProfileRFC9580:
+		// An empty profile falls back to ProfileRFC4880 to keep configurations
+		// working which do not use DefaultConfig.
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Profile must be one of %q or %q", ProfileRFC4880, ProfileRFC9580)
 	}
 
 	newService := &Service{
-		pass: config.Pass,
+		keyRing: keyRing,
+		passes:  passes,
+		profile: config.Profile,
+		signers: signers,
 	}
 
 	return newService, nil
@@ -44,39 +159,227 @@ func New(config Config) (*Service, error) {
 // Service implements the GPG decryption value modifier.
 type Service struct {
 	// Settings.
-	pass string
+	keyRing openpgp.EntityList
+	passes  []string
+	profile string
+	signers openpgp.EntityList
+}
+
+// Result describes a decrypted GPG message and how it was decrypted.
+type Result struct {
+	// Value is the plain text of the GPG message.
+	Value []byte
+	// PassIndex is the position of the passphrase which decrypted the GPG
+	// message, counting Pass first, when configured, followed by Passes. It is
+	// -1 when the GPG message was decrypted using a private key.
+	PassIndex int
+	// KeyID is the ID of the private key which decrypted the GPG message. It
+	// is 0 when the GPG message was decrypted using a passphrase.
+	KeyID uint64
 }
 
 func (s *Service) Modify(value []byte) ([]byte, error) {
+	result, err := s.Decrypt(value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return result.Value, nil
+}
+
+// Decrypt decrypts the given GPG message like Modify, but additionally reports
+// which passphrase or private key decrypted it. This can be used to find values
+// still encrypted using an old passphrase when rotating passphrases.
+func (s *Service) Decrypt(value []byte) (Result, error) {
 	if len(value) == 0 {
-		return value, nil
+		return Result{Value: value, PassIndex: -1}, nil
 	}
 
-	buf := bytes.NewBuffer(value)
-	decoder, err := armor.Decode(buf)
+	message, err := readMessage(value)
 	if err != nil {
-		return nil, microerror.Mask(err)
+		return Result{}, microerror.Mask(err)
 	}
 
-	promptFunc := func() func([]openpgp.Key, bool) ([]byte, error) {
-		retried := false
-		return func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
-			if !retried {
-				retried = true
-				return []byte(s.pass), nil
+	if s.profile == ProfileRFC9580 {
+		err = checkRFC9580(message)
+		if err != nil {
+			return Result{}, microerror.Mask(err)
+		}
+	}
+
+	// Passphrases are tried one after another, each reading the GPG message
+	// from scratch. A wrong passphrase is not necessarily detected when
+	// decrypting the session key, but only when reading the whole message
+	// fails its integrity check. Private keys are tried along with the first
+	// passphrase.
+	passes := s.passes
+	if len(passes) == 0 {
+		passes = []string{""}
+	}
+
+	for i, pass := range passes {
+		details, b, prompted, err := s.decrypt(message, pass)
+		if prompted && err != nil {
+			continue
+		} else if err != nil {
+			return Result{}, microerror.Mask(err)
+		}
+
+		if len(s.signers) != 0 {
+			err = s.verify(details)
+			if err != nil {
+				return Result{}, microerror.Mask(err)
 			}
-			return nil, microerror.Maskf(wrongGPGPasswordError, "Decryption failed with given GPG password")
 		}
-	}()
-	details, err := openpgp.ReadMessage(decoder.Body, nil, promptFunc, nil)
+
+		result := Result{
+			Value:     b,
+			PassIndex: -1,
+		}
+		if prompted {
+			result.PassIndex = i
+		} else if details.DecryptedWith.PublicKey != nil {
+			result.KeyID = details.DecryptedWith.PublicKey.KeyId
+		}
+
+		return result, nil
+	}
+
+	return Result{}, microerror.Maskf(wrongGPGPasswordError, "Decryption failed with given GPG password")
+}
+
+// decrypt reads the given binary GPG message using the configured private keys
+// and the given passphrase. prompted reports whether the passphrase was used.
+func (s *Service) decrypt(message []byte, pass string) (*openpgp.MessageDetails, []byte, bool, error) {
+	// The prompt function is only called when none of the configured private
+	// keys can decrypt the message. Private keys are already unlocked at this
+	// point, so the passphrase is only provided for symmetrically encrypted
+	// messages.
+	prompted := false
+	promptFunc := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if !symmetric {
+			return nil, microerror.Maskf(noMatchingKeyError, "Decryption failed with given GPG keys")
+		}
+		if !prompted && pass != "" {
+			prompted = true
+			return []byte(pass), nil
+		}
+		return nil, microerror.Maskf(wrongGPGPasswordError, "Decryption failed with given GPG password")
+	}
+	// Signers are looked up in the same key ring as decryption keys. Whether
+	// a signer is trusted is checked separately.
+	keyRing := append(append(openpgp.EntityList{}, s.keyRing...), s.signers...)
+	details, err := openpgp.ReadMessage(bytes.NewReader(message), keyRing, promptFunc, nil)
+	if err == pgperrors.ErrKeyIncorrect {
+		return nil, nil, false, microerror.Maskf(noMatchingKeyError, "Decryption failed with given GPG keys")
+	} else if err != nil {
+		return nil, nil, prompted, microerror.Mask(err)
+	}
+
+	// The signature can only be checked after the whole body was read.
+	b, err := io.ReadAll(details.UnverifiedBody)
+	if err != nil {
+		return nil, nil, prompted, microerror.Mask(err)
+	}
+
+	return details, b, prompted, nil
+}
+
+func (s *Service) verify(details *openpgp.MessageDetails) error {
+	if !details.IsSigned {
+		return microerror.Maskf(missingSignatureError, "GPG message must be signed")
+	}
+	if details.SignedBy == nil || !containsEntity(s.signers, details.SignedBy.Entity) {
+		return microerror.Maskf(unknownSignerError, "GPG message signed by unknown key %X", details.SignedByKeyId)
+	}
+	if details.SignatureError != nil {
+		return microerror.Maskf(invalidSignatureError, "%s", details.SignatureError.Error())
+	}
+
+	return nil
+}
+
+// readMessage returns the binary OpenPGP message of the given value, which is
+// either armored, base64 encoded on a single line or binary.
+func readMessage(value []byte) ([]byte, error) {
+	// Binary OpenPGP messages always start with a packet tag having its most
+	// significant bit set, which is never the case for armored or base64
+	// encoded messages.
+	if value[0]&0x80 != 0 {
+		return value, nil
+	}
+
+	trimmed := bytes.TrimSpace(value)
+	if !bytes.HasPrefix(trimmed, []byte("-----BEGIN ")) {
+		message, err := base64.StdEncoding.DecodeString(string(trimmed))
+		if err != nil {
+			return nil, microerror.Maskf(invalidArmorError, "GPG message must be armored, base64 encoded or binary: %s", err.Error())
+		}
+
+		return message, nil
+	}
+
+	decoder, err := armor.Decode(bytes.NewReader(value))
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
+	// Older versions of gpg/encrypt armored GPG messages as signatures. Both
+	// block types are accepted so that existing values can still be
+	// decrypted.
+	if decoder.Type != gpgarmor.MessageType && decoder.Type != openpgp.SignatureType {
+		return nil, microerror.Maskf(invalidArmorError, "GPG message must not be armored as %q", decoder.Type)
+	}
 
-	b, err := io.ReadAll(details.UnverifiedBody)
+	message, err := io.ReadAll(decoder.Body)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	return b, nil
+	return message, nil
+}
+
+// checkRFC9580 ensures the given binary OpenPGP message uses an AEAD encrypted
+// data packet as defined by RFC 9580.
+func checkRFC9580(message []byte) error {
+	packets := packet.NewReader(bytes.NewReader(message))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		switch p := p.(type) {
+		case *packet.SymmetricKeyEncrypted, *packet.EncryptedKey:
+			continue
+		case *packet.SymmetricallyEncrypted:
+			if p.Version == 2 {
+				return nil
+			}
+		}
+
+		return microerror.Maskf(unsupportedProfileError, "GPG message must use AEAD encrypted data packets as defined by RFC 9580")
+	}
+}
+
+func containsEntity(list openpgp.EntityList, item *openpgp.Entity) bool {
+	for _, e := range list {
+		if bytes.Equal(e.PrimaryKey.Fingerprint, item.PrimaryKey.Fingerprint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isLocked(e *openpgp.Entity) bool {
+	if e.PrivateKey != nil && !e.PrivateKey.Dummy() && e.PrivateKey.Encrypted {
+		return true
+	}
+	for _, k := range e.Subkeys {
+		if k.PrivateKey != nil && !k.PrivateKey.Dummy() && k.PrivateKey.Encrypted {
+			return true
+		}
+	}
+
+	return false
 }
@@ -2,6 +2,13 @@ package decrypt
 
 import (
 	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+
+	"github.com/giantswarm/valuemodifier/gpg/encrypt"
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgtest"
 )
 
 func Test_GPG_Decrypt_Service_Modify(t *testing.T) {
@@ -29,6 +36,128 @@ oHm+HyYpiGLqnC/rncq3SRJ9z0xSEbhS5l+Dp3xMTGniaNEU2xtt72M35kS+HA==
 	}
 }
 
+func Test_GPG_Decrypt_Service_Modify_ArmorType(t *testing.T) {
+	body := `
+
+wx4EBwMItflyy+CkVHfgNJ9CwJz0SXR8DVmT+GrIQpbSPAFfOlMN/2J8XF2/hRCm
+oHm+HyYpiGLqnC/rncq3SRJ9z0xSEbhS5l+Dp3xMTGniaNEU2xtt72M35kS+HA==
+=R5GK
+` // "hello world"
+
+	testCases := []struct {
+		Type         string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, GPG messages armored as messages are decrypted.
+		{
+			Type: "PGP MESSAGE",
+		},
+		// Test case 2, GPG messages armored as signatures by older versions of
+		// gpg/encrypt are decrypted.
+		{
+			Type: "PGP SIGNATURE",
+		},
+		// Test case 3, other armor block types are rejected.
+		{
+			Type:         "PGP PRIVATE KEY BLOCK",
+			ErrorMatcher: IsInvalidArmor,
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.Pass = "foo"
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		value := []byte("-----BEGIN " + tc.Type + "-----" + body + "-----END " + tc.Type + "-----")
+		modified, err := newService.Modify(value)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != "hello world" {
+			t.Fatal("test", i+1, "expected", "hello world", "got", string(modified))
+		}
+	}
+}
+
+func Test_GPG_Decrypt_Service_Modify_Output(t *testing.T) {
+	expected := []byte("hello world")
+
+	testCases := []struct {
+		Value        func(t *testing.T, output string) []byte
+		Output       string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, armored GPG messages are detected.
+		{
+			Output: encrypt.OutputArmored,
+		},
+		// Test case 2, binary GPG messages are detected.
+		{
+			Output: encrypt.OutputBinary,
+		},
+		// Test case 3, compact GPG messages are detected.
+		{
+			Output: encrypt.OutputCompact,
+		},
+		// Test case 4, compact GPG messages surrounded by whitespace are
+		// detected, e.g. when read from env files.
+		{
+			Value: func(t *testing.T, output string) []byte {
+				return append(append([]byte(" "), encryptValue(t, expected, output)...), '\n')
+			},
+			Output: encrypt.OutputCompact,
+		},
+		// Test case 5, values being neither armored, nor base64 encoded nor
+		// binary are rejected.
+		{
+			Value: func(t *testing.T, output string) []byte {
+				return []byte("hello world")
+			},
+			ErrorMatcher: IsInvalidArmor,
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.Pass = "foo"
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		var value []byte
+		if tc.Value != nil {
+			value = tc.Value(t, tc.Output)
+		} else {
+			value = encryptValue(t, expected, tc.Output)
+		}
+
+		modified, err := newService.Modify(value)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != string(expected) {
+			t.Fatal("test", i+1, "expected", string(expected), "got", string(modified))
+		}
+	}
+}
+
 func Test_GPG_Decrypt_Service_Modify_Empty(t *testing.T) {
 	config := DefaultConfig()
 	config.Pass = "foo"
@@ -48,3 +177,452 @@ func Test_GPG_Decrypt_Service_Modify_Empty(t *testing.T) {
 		t.Fatal("expected", expected, "got", modified)
 	}
 }
+
+func Test_GPG_Decrypt_Service_Modify_PrivateKeys(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+	bob := gpgtest.NewEntity(t, "bob")
+	carol := gpgtest.NewEntity(t, "carol")
+
+	expected := []byte("hello world")
+
+	testCases := []struct {
+		Encrypt      encrypt.Config
+		Decrypt      Config
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, a message encrypted to multiple recipients is decrypted
+		// with one of the private keys.
+		{
+			Encrypt: encrypt.Config{
+				KeyRing: openpgp.EntityList{alice, bob},
+			},
+			Decrypt: Config{
+				KeyRing: openpgp.EntityList{bob},
+			},
+		},
+		// Test case 2, a message is decrypted with an armored private key.
+		{
+			Encrypt: encrypt.Config{
+				KeyRing: openpgp.EntityList{alice},
+			},
+			Decrypt: Config{
+				PrivateKeys: []string{gpgtest.ArmoredPrivateKey(t, alice, "")},
+			},
+		},
+		// Test case 3, a symmetrically encrypted message is decrypted using the
+		// passphrase even though private keys are configured.
+		{
+			Encrypt: encrypt.Config{
+				Pass: "foo",
+			},
+			Decrypt: Config{
+				Pass:    "foo",
+				KeyRing: openpgp.EntityList{alice},
+			},
+		},
+		// Test case 4, a message not encrypted to any of the configured keys
+		// cannot be decrypted.
+		{
+			Encrypt: encrypt.Config{
+				KeyRing: openpgp.EntityList{alice},
+			},
+			Decrypt: Config{
+				KeyRing: openpgp.EntityList{carol},
+			},
+			ErrorMatcher: IsNoMatchingKey,
+		},
+		// Test case 5, a symmetrically encrypted message cannot be decrypted
+		// with the wrong passphrase.
+		{
+			Encrypt: encrypt.Config{
+				Pass: "foo",
+			},
+			Decrypt: Config{
+				Pass:    "bar",
+				KeyRing: openpgp.EntityList{alice},
+			},
+			ErrorMatcher: IsWrongGPGPassword,
+		},
+	}
+
+	for i, tc := range testCases {
+		encryptService, err := encrypt.New(tc.Encrypt)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		newService, err := New(tc.Decrypt)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		encrypted, err := encryptService.Modify(expected)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		modified, err := newService.Modify(encrypted)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != string(expected) {
+			t.Fatal("test", i+1, "expected", string(expected), "got", string(modified))
+		}
+	}
+}
+
+func Test_GPG_Decrypt_Service_Modify_ProtectedPrivateKey(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+	armored := gpgtest.ArmoredPrivateKey(t, alice, "secret")
+
+	encryptConfig := encrypt.DefaultConfig()
+	encryptConfig.KeyRing = openpgp.EntityList{alice}
+	encryptService, err := encrypt.New(encryptConfig)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	{
+		config := DefaultConfig()
+		config.PrivateKeys = []string{armored}
+		_, err := New(config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("expected", true, "got", err)
+		}
+	}
+
+	{
+		config := DefaultConfig()
+		config.PrivateKeys = []string{armored}
+		config.KeyPass = "wrong"
+		_, err := New(config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("expected", true, "got", err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.PrivateKeys = []string{armored}
+	config.KeyPass = "secret"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	expected := []byte("hello world")
+	encrypted, err := encryptService.Modify(expected)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	modified, err := newService.Modify(encrypted)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(modified) != string(expected) {
+		t.Fatal("expected", string(expected), "got", string(modified))
+	}
+}
+
+func Test_GPG_Decrypt_Service_Modify_Signed(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+	bob := gpgtest.NewEntity(t, "bob")
+
+	expected := []byte("hello world")
+
+	testCases := []struct {
+		Signer        *openpgp.Entity
+		SignerKeyRing openpgp.EntityList
+		ErrorMatcher  func(error) bool
+	}{
+		// Test case 1, a message signed by a trusted signer is decrypted.
+		{
+			Signer:        bob,
+			SignerKeyRing: openpgp.EntityList{bob},
+		},
+		// Test case 2, a message signed by an untrusted signer is rejected, even
+		// though the signer is known as recipient.
+		{
+			Signer:        alice,
+			SignerKeyRing: openpgp.EntityList{bob},
+			ErrorMatcher:  IsUnknownSigner,
+		},
+		// Test case 3, a message which is not signed is rejected.
+		{
+			Signer:        nil,
+			SignerKeyRing: openpgp.EntityList{bob},
+			ErrorMatcher:  IsMissingSignature,
+		},
+		// Test case 4, signatures are not required without trusted signers.
+		{
+			Signer:        nil,
+			SignerKeyRing: nil,
+		},
+	}
+
+	for i, tc := range testCases {
+		encryptConfig := encrypt.DefaultConfig()
+		encryptConfig.KeyRing = openpgp.EntityList{alice}
+		encryptConfig.Signer = tc.Signer
+		encryptService, err := encrypt.New(encryptConfig)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		config := DefaultConfig()
+		config.KeyRing = openpgp.EntityList{alice}
+		config.SignerKeyRing = tc.SignerKeyRing
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		encrypted, err := encryptService.Modify(expected)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		modified, err := newService.Modify(encrypted)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != string(expected) {
+			t.Fatal("test", i+1, "expected", string(expected), "got", string(modified))
+		}
+	}
+}
+
+func Test_GPG_Decrypt_Service_Modify_Profile(t *testing.T) {
+	expected := []byte("hello world")
+
+	testCases := []struct {
+		EncryptProfile string
+		DecryptProfile string
+		ErrorMatcher   func(error) bool
+	}{
+		// Test case 1, legacy messages are decrypted by default.
+		{
+			EncryptProfile: encrypt.ProfileRFC4880,
+			DecryptProfile: ProfileRFC4880,
+		},
+		// Test case 2, RFC 9580 messages are decrypted by default.
+		{
+			EncryptProfile: encrypt.ProfileRFC9580,
+			DecryptProfile: ProfileRFC4880,
+		},
+		// Test case 3, RFC 9580 messages are decrypted with the RFC 9580
+		// profile.
+		{
+			EncryptProfile: encrypt.ProfileRFC9580,
+			DecryptProfile: ProfileRFC9580,
+		},
+		// Test case 4, legacy messages are rejected with the RFC 9580 profile.
+		{
+			EncryptProfile: encrypt.ProfileRFC4880,
+			DecryptProfile: ProfileRFC9580,
+			ErrorMatcher:   IsUnsupportedProfile,
+		},
+	}
+
+	for i, tc := range testCases {
+		encryptConfig := encrypt.DefaultConfig()
+		encryptConfig.Pass = "foo"
+		encryptConfig.Profile = tc.EncryptProfile
+		// Keep Argon2 cheap so the tests stay fast.
+		encryptConfig.Argon2 = &s2k.Argon2Config{
+			NumberOfPasses:      1,
+			DegreeOfParallelism: 1,
+			Memory:              64,
+		}
+		if tc.EncryptProfile != encrypt.ProfileRFC9580 {
+			encryptConfig.Argon2 = nil
+		}
+		encryptService, err := encrypt.New(encryptConfig)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		config := DefaultConfig()
+		config.Pass = "foo"
+		config.Profile = tc.DecryptProfile
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		encrypted, err := encryptService.Modify(expected)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		modified, err := newService.Modify(encrypted)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != string(expected) {
+			t.Fatal("test", i+1, "expected", string(expected), "got", string(modified))
+		}
+	}
+}
+
+func Test_GPG_Decrypt_Service_Decrypt(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+	aliceKey, ok := alice.EncryptionKey(time.Now())
+	if !ok {
+		t.Fatal("expected", true, "got", false)
+	}
+
+	expected := []byte("hello world")
+
+	testCases := []struct {
+		Encrypt           encrypt.Config
+		Decrypt           Config
+		ExpectedPassIndex int
+		ExpectedKeyID     uint64
+		ErrorMatcher      func(error) bool
+	}{
+		// Test case 1, the first passphrase decrypts the message.
+		{
+			Encrypt: encrypt.Config{
+				Pass: "new",
+			},
+			Decrypt: Config{
+				Passes: []string{"new", "old"},
+			},
+			ExpectedPassIndex: 0,
+		},
+		// Test case 2, a later passphrase decrypts the message.
+		{
+			Encrypt: encrypt.Config{
+				Pass: "old",
+			},
+			Decrypt: Config{
+				Passes: []string{"new", "older", "old"},
+			},
+			ExpectedPassIndex: 2,
+		},
+		// Test case 3, Pass is tried before Passes.
+		{
+			Encrypt: encrypt.Config{
+				Pass: "old",
+			},
+			Decrypt: Config{
+				Pass:   "new",
+				Passes: []string{"old"},
+			},
+			ExpectedPassIndex: 1,
+		},
+		// Test case 4, passphrases are tried for RFC 9580 messages.
+		{
+			Encrypt: encrypt.Config{
+				Pass:    "old",
+				Profile: encrypt.ProfileRFC9580,
+				Argon2: &s2k.Argon2Config{
+					NumberOfPasses:      1,
+					DegreeOfParallelism: 1,
+					Memory:              64,
+				},
+			},
+			Decrypt: Config{
+				Passes: []string{"new", "old"},
+			},
+			ExpectedPassIndex: 1,
+		},
+		// Test case 5, the private key which decrypted the message is reported.
+		{
+			Encrypt: encrypt.Config{
+				KeyRing: openpgp.EntityList{alice},
+			},
+			Decrypt: Config{
+				Passes:  []string{"new", "old"},
+				KeyRing: openpgp.EntityList{alice},
+			},
+			ExpectedPassIndex: -1,
+			ExpectedKeyID:     aliceKey.PublicKey.KeyId,
+		},
+		// Test case 6, the message cannot be decrypted with any of the
+		// passphrases.
+		{
+			Encrypt: encrypt.Config{
+				Pass: "foo",
+			},
+			Decrypt: Config{
+				Passes: []string{"new", "old"},
+			},
+			ErrorMatcher: IsWrongGPGPassword,
+		},
+	}
+
+	for i, tc := range testCases {
+		encryptService, err := encrypt.New(tc.Encrypt)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		newService, err := New(tc.Decrypt)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		encrypted, err := encryptService.Modify(expected)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		result, err := newService.Decrypt(encrypted)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(result.Value) != string(expected) {
+			t.Fatal("test", i+1, "expected", string(expected), "got", string(result.Value))
+		}
+		if result.PassIndex != tc.ExpectedPassIndex {
+			t.Fatal("test", i+1, "expected", tc.ExpectedPassIndex, "got", result.PassIndex)
+		}
+		if result.KeyID != tc.ExpectedKeyID {
+			t.Fatal("test", i+1, "expected", tc.ExpectedKeyID, "got", result.KeyID)
+		}
+	}
+}
+
+func Test_GPG_Decrypt_New_Error(t *testing.T) {
+	config := DefaultConfig()
+	config.Passes = []string{"foo", ""}
+	_, err := New(config)
+	if !IsInvalidConfig(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func encryptValue(t *testing.T, value []byte, output string) []byte {
+	config := encrypt.DefaultConfig()
+	config.Pass = "foo"
+	config.Output = output
+	newService, err := encrypt.New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	b, err := newService.Modify(value)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return b
+}
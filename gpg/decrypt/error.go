@@ -19,3 +19,57 @@ var wrongGPGPasswordError = &microerror.Error{
 func IsWrongGPGPassword(err error) bool {
 	return microerror.Cause(err) == wrongGPGPasswordError
 }
+
+var noMatchingKeyError = &microerror.Error{
+	Kind: "noMatchingKeyError",
+}
+
+// IsNoMatchingKey asserts noMatchingKeyError.
+func IsNoMatchingKey(err error) bool {
+	return microerror.Cause(err) == noMatchingKeyError
+}
+
+var invalidSignatureError = &microerror.Error{
+	Kind: "invalidSignatureError",
+}
+
+// IsInvalidSignature asserts invalidSignatureError.
+func IsInvalidSignature(err error) bool {
+	return microerror.Cause(err) == invalidSignatureError
+}
+
+var missingSignatureError = &microerror.Error{
+	Kind: "missingSignatureError",
+}
+
+// IsMissingSignature asserts missingSignatureError.
+func IsMissingSignature(err error) bool {
+	return microerror.Cause(err) == missingSignatureError
+}
+
+var unknownSignerError = &microerror.Error{
+	Kind: "unknownSignerError",
+}
+
+// IsUnknownSigner asserts unknownSignerError.
+func IsUnknownSigner(err error) bool {
+	return microerror.Cause(err) == unknownSignerError
+}
+
+var unsupportedProfileError = &microerror.Error{
+	Kind: "unsupportedProfileError",
+}
+
+// IsUnsupportedProfile asserts unsupportedProfileError.
+func IsUnsupportedProfile(err error) bool {
+	return microerror.Cause(err) == unsupportedProfileError
+}
+
+var invalidArmorError = &microerror.Error{
+	Kind: "invalidArmorError",
+}
+
+// IsInvalidArmor asserts invalidArmorError.
+func IsInvalidArmor(err error) bool {
+	return microerror.Cause(err) == invalidArmorError
+}
@@ -2,19 +2,119 @@ package encrypt
 
 import (
 	"bytes"
+	"crypto"
+	"encoding/base64"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
 	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgarmor"
+)
+
+const (
+	// ProfileRFC4880 produces classic OpenPGP messages as defined by RFC 4880,
+	// which are understood by all OpenPGP implementations.
+	ProfileRFC4880 = "rfc4880"
+	// ProfileRFC9580 produces OpenPGP messages as defined by RFC 9580, using
+	// AEAD encrypted data packets, also known as SEIPD version 2, and Argon2
+	// for deriving keys from passphrases. Note that Argon2 is memory hard by
+	// design and by default requires 64 MiB of memory per encrypted value.
+	ProfileRFC9580 = "rfc9580"
 )
 
+const (
+	// OutputArmored produces armored GPG messages spanning multiple lines.
+	OutputArmored = "armored"
+	// OutputBinary produces raw binary GPG messages, e.g. to be encoded by the
+	// base64 encoding value modifier afterwards.
+	OutputBinary = "binary"
+	// OutputCompact produces base64 encoded binary GPG messages on a single
+	// line, without any armor.
+	OutputCompact = "compact"
+)
+
+// MessageType is the armor block type of GPG messages.
+const MessageType = gpgarmor.MessageType
+
 // Config represents the configuration used to create a new GPG encryption
 // value modifier.
 type Config struct {
 	// Settings.
 
-	// Pass is the passphrase used to encrypt GPG messages.
+	// Pass is the passphrase used to encrypt GPG messages symmetrically. Pass
+	// must not be used together with PublicKeys or KeyRing.
 	Pass string
+	// PublicKeys are the armored public keys of the recipients GPG messages
+	// are encrypted to. Each item may contain multiple keys.
+	PublicKeys []string
+	// KeyRing holds the recipients GPG messages are encrypted to, in addition to
+	// the ones defined by PublicKeys.
+	KeyRing openpgp.EntityList
+	// SignerPrivateKey is the armored private key used to sign GPG messages
+	// while encrypting them. Signing requires recipients. SignerPrivateKey must
+	// not be used together with Signer.
+	SignerPrivateKey string
+	// SignerKeyPass is the passphrase used to unlock a passphrase protected
+	// private key of SignerPrivateKey or Signer.
+	SignerKeyPass string
+	// Signer is the entity used to sign GPG messages while encrypting them.
+	// Signing requires recipients. Signer must not be used together with
+	// SignerPrivateKey.
+	Signer *openpgp.Entity
+
+	// Cipher is the symmetric cipher used to encrypt GPG messages. It is one of
+	// packet.CipherAES128, packet.CipherAES192 or packet.CipherAES256. When
+	// empty the library default is used.
+	Cipher packet.CipherFunction
+	// Hash is the hash function used for signatures and for the S2K key
+	// derivation of symmetrically encrypted GPG messages. When empty the
+	// library default is used.
+	Hash crypto.Hash
+	// Compression is the compression algorithm applied before encrypting GPG
+	// messages. It is one of packet.CompressionNone, packet.CompressionZIP or
+	// packet.CompressionZLIB.
+	Compression packet.CompressionAlgo
+	// CompressionLevel is the compression level between -1 and 9 used when
+	// Compression is not packet.CompressionNone. -1 selects the default level.
+	// 0 is rejected together with ZIP or ZLIB, since it would store values
+	// uncompressed. Use packet.CompressionNone instead.
+	CompressionLevel int
+	// S2KMode is the S2K mode used to derive keys from Pass. It is either
+	// s2k.IteratedSaltedS2K or s2k.Argon2S2K. When empty the library default is
+	// used.
+	S2KMode s2k.Mode
+	// S2KCount is the iteration count used with s2k.IteratedSaltedS2K. It must
+	// be between 65536 and 65011712. When empty the library default is used.
+	S2KCount int
+	// Argon2 defines the parameters used with s2k.Argon2S2K. When nil the
+	// library defaults are used.
+	Argon2 *s2k.Argon2Config
+	// AEADMode enables AEAD encryption using the given mode. It is one of
+	// packet.AEADModeEAX, packet.AEADModeOCB or packet.AEADModeGCM. When empty
+	// GPG messages are encrypted without AEAD, unless Profile is
+	// ProfileRFC9580.
+	AEADMode packet.AEADMode
+	// Profile is the OpenPGP profile GPG messages are produced with. It is
+	// either ProfileRFC4880 or ProfileRFC9580. ProfileRFC9580 defaults
+	// AEADMode to packet.AEADModeOCB and S2KMode to s2k.Argon2S2K. Messages
+	// encrypted to recipients only use AEAD when all recipient keys support it.
+	Profile string
+
+	// ArmorHeaders are added to the armor of every GPG message, e.g. to
+	// document the key or tooling used to encrypt values. Keys must not be
+	// empty and must not contain colons or line breaks. Values must not
+	// contain line breaks.
+	ArmorHeaders map[string]string
+	// Output is the format GPG messages are returned in. It is one of
+	// OutputArmored, OutputBinary or OutputCompact. ArmorHeaders can only be
+	// used with OutputArmored.
+	Output string
 }
 
 // DefaultConfig provides a default configuration to create a new GPG encryption
@@ -22,19 +122,132 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Settings.
-		Pass: "",
+		Pass:             "",
+		PublicKeys:       nil,
+		KeyRing:          nil,
+		SignerPrivateKey: "",
+		SignerKeyPass:    "",
+		Signer:           nil,
+
+		Cipher:           0,
+		Hash:             0,
+		Compression:      packet.CompressionNone,
+		CompressionLevel: packet.DefaultCompression,
+		S2KMode:          0,
+		S2KCount:         0,
+		Argon2:           nil,
+		AEADMode:         0,
+		Profile:          ProfileRFC4880,
+
+		ArmorHeaders: nil,
+		Output:       OutputArmored,
 	}
 }
 
 // New creates a new configured GPG encryption value modifier.
 func New(config Config) (*Service, error) {
+	var recipients openpgp.EntityList
+	{
+		recipients = append(recipients, config.KeyRing...)
+
+		for _, k := range config.PublicKeys {
+			entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(k))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.PublicKeys must only contain armored public keys: %s", err.Error())
+			}
+			recipients = append(recipients, entities...)
+		}
+
+		for _, r := range recipients {
+			_, ok := r.EncryptionKey(time.Now())
+			if !ok {
+				return nil, microerror.Maskf(invalidConfigError, "recipient %X must have a valid encryption key", r.PrimaryKey.Fingerprint)
+			}
+		}
+	}
+
 	// Settings.
-	if config.Pass == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.Pass must not be empty")
+	if config.Pass == "" && len(recipients) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pass or config.PublicKeys or config.KeyRing must not be empty")
+	}
+	if config.Pass != "" && len(recipients) != 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pass must be empty when config.PublicKeys or config.KeyRing provided")
+	}
+
+	var signer *openpgp.Entity
+	{
+		if config.SignerPrivateKey != "" && config.Signer != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.SignerPrivateKey must be empty when config.Signer provided")
+		}
+
+		signer = config.Signer
+		if config.SignerPrivateKey != "" {
+			entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(config.SignerPrivateKey))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.SignerPrivateKey must be an armored private key: %s", err.Error())
+			}
+			if len(entities) != 1 {
+				return nil, microerror.Maskf(invalidConfigError, "config.SignerPrivateKey must contain exactly one key")
+			}
+			signer = entities[0]
+		}
+
+		if signer != nil {
+			if len(recipients) == 0 {
+				return nil, microerror.Maskf(invalidConfigError, "config.PublicKeys or config.KeyRing must not be empty when signing")
+			}
+			if signer.PrivateKey == nil {
+				return nil, microerror.Maskf(invalidConfigError, "signer %X must be a private key", signer.PrimaryKey.Fingerprint)
+			}
+			key, ok := signer.SigningKey(time.Now())
+			if !ok {
+				return nil, microerror.Maskf(invalidConfigError, "signer %X must have a valid signing key", signer.PrimaryKey.Fingerprint)
+			}
+			if key.PrivateKey.Encrypted {
+				if config.SignerKeyPass == "" {
+					return nil, microerror.Maskf(invalidConfigError, "config.SignerKeyPass must not be empty when signer %X is passphrase protected", signer.PrimaryKey.Fingerprint)
+				}
+				err := signer.DecryptPrivateKeys([]byte(config.SignerKeyPass))
+				if err != nil {
+					return nil, microerror.Maskf(invalidConfigError, "config.SignerKeyPass must unlock signer %X: %s", signer.PrimaryKey.Fingerprint, err.Error())
+				}
+			}
+		}
+	}
+
+	packetConfig, err := newPacketConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for k, v := range config.ArmorHeaders {
+		if k == "" || strings.ContainsAny(k, ":\r\n") || strings.ContainsAny(v, "\r\n") {
+			return nil, microerror.Maskf(invalidConfigError, "config.ArmorHeaders must not contain header %q", k)
+		}
+	}
+
+	output := config.Output
+	switch output {
+	case "":
+		// An empty output falls back to OutputArmored to keep configurations
+		// working which do not use DefaultConfig.
+		output = OutputArmored
+	case OutputArmored:
+	case OutputBinary, OutputCompact:
+		if len(config.ArmorHeaders) != 0 {
+			return nil, microerror.Maskf(invalidConfigError, "config.ArmorHeaders must be empty when config.Output is %q", output)
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Output must be one of %q, %q or %q", OutputArmored, OutputBinary, OutputCompact)
 	}
 
 	newService := &Service{
-		pass: config.Pass,
+		armorHeaders: config.ArmorHeaders,
+		output:       output,
+		packetConfig: packetConfig,
+		pass:         config.Pass,
+		recipients:   recipients,
+		signer:       signer,
 	}
 
 	return newService, nil
@@ -43,7 +256,12 @@ func New(config Config) (*Service, error) {
 // Service implements the GPG encryption value modifier.
 type Service struct {
 	// Settings.
-	pass string
+	armorHeaders map[string]string
+	output       string
+	packetConfig *packet.Config
+	pass         string
+	recipients   openpgp.EntityList
+	signer       *openpgp.Entity
 }
 
 func (s *Service) Modify(value []byte) ([]byte, error) {
@@ -51,13 +269,26 @@ func (s *Service) Modify(value []byte) ([]byte, error) {
 		return value, nil
 	}
 
+	var err error
+
 	buf := bytes.NewBuffer(nil)
-	encoder, err := armor.Encode(buf, openpgp.SignatureType, nil)
-	if err != nil {
-		return nil, microerror.Mask(err)
+	var out io.Writer = buf
+
+	var encoder io.WriteCloser
+	if s.output == OutputArmored {
+		encoder, err = armor.Encode(buf, MessageType, s.armorHeaders)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		out = encoder
 	}
 
-	encrypter, err := openpgp.SymmetricallyEncrypt(encoder, []byte(s.pass), nil, nil)
+	var encrypter io.WriteCloser
+	if len(s.recipients) != 0 {
+		encrypter, err = openpgp.Encrypt(out, s.recipients, s.signer, nil, s.packetConfig)
+	} else {
+		encrypter, err = openpgp.SymmetricallyEncrypt(out, []byte(s.pass), nil, s.packetConfig)
+	}
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
@@ -72,10 +303,95 @@ func (s *Service) Modify(value []byte) ([]byte, error) {
 		return nil, microerror.Mask(err)
 	}
 
-	err = encoder.Close()
-	if err != nil {
-		return nil, microerror.Mask(err)
+	if encoder != nil {
+		err = encoder.Close()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if s.output == OutputCompact {
+		return []byte(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
 	}
 
 	return buf.Bytes(), nil
 }
+
+func newPacketConfig(config Config) (*packet.Config, error) {
+	switch config.Profile {
+	case "", ProfileRFC4880:
+		// An empty profile falls back to ProfileRFC4880 to keep configurations
+		// working which do not use DefaultConfig.
+	case ProfileRFC9580:
+		if config.AEADMode == 0 {
+			config.AEADMode = packet.AEADModeOCB
+		}
+		if config.S2KMode == 0 && config.S2KCount == 0 {
+			config.S2KMode = s2k.Argon2S2K
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Profile must be one of %q or %q", ProfileRFC4880, ProfileRFC9580)
+	}
+
+	switch config.Cipher {
+	case 0, packet.CipherAES128, packet.CipherAES192, packet.CipherAES256:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Cipher must be one of AES128, AES192 or AES256")
+	}
+	if config.Hash != 0 && !config.Hash.Available() {
+		return nil, microerror.Maskf(invalidConfigError, "config.Hash must be an available hash function")
+	}
+	switch config.Compression {
+	case packet.CompressionNone, packet.CompressionZIP, packet.CompressionZLIB:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Compression must be one of none, ZIP or ZLIB")
+	}
+	if config.CompressionLevel < packet.DefaultCompression || config.CompressionLevel > packet.BestCompression {
+		return nil, microerror.Maskf(invalidConfigError, "config.CompressionLevel must be between %d and %d", packet.DefaultCompression, packet.BestCompression)
+	}
+	if config.Compression != packet.CompressionNone && config.CompressionLevel == packet.NoCompression {
+		return nil, microerror.Maskf(invalidConfigError, "config.CompressionLevel must not be %d when config.Compression is ZIP or ZLIB", packet.NoCompression)
+	}
+	switch config.S2KMode {
+	case 0, s2k.IteratedSaltedS2K, s2k.Argon2S2K:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.S2KMode must be one of iterated and salted or Argon2")
+	}
+	if config.S2KCount != 0 && (config.S2KCount < 65536 || config.S2KCount > 65011712) {
+		return nil, microerror.Maskf(invalidConfigError, "config.S2KCount must be between 65536 and 65011712")
+	}
+	if config.Argon2 != nil && config.S2KMode != s2k.Argon2S2K {
+		return nil, microerror.Maskf(invalidConfigError, "config.Argon2 must be empty when config.S2KMode is not Argon2")
+	}
+	switch config.AEADMode {
+	case 0, packet.AEADModeEAX, packet.AEADModeOCB, packet.AEADModeGCM:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.AEADMode must be one of EAX, OCB or GCM")
+	}
+
+	packetConfig := &packet.Config{
+		DefaultCipher:          config.Cipher,
+		DefaultHash:            config.Hash,
+		DefaultCompressionAlgo: config.Compression,
+		CompressionConfig: &packet.CompressionConfig{
+			Level: config.CompressionLevel,
+		},
+	}
+
+	if config.S2KMode != 0 || config.S2KCount != 0 {
+		packetConfig.S2KConfig = &s2k.Config{
+			S2KMode:      config.S2KMode,
+			Hash:         config.Hash,
+			S2KCount:     config.S2KCount,
+			Argon2Config: config.Argon2,
+		}
+	}
+
+	if config.AEADMode != 0 {
+		packetConfig.AEADConfig = &packet.AEADConfig{
+			DefaultMode: config.AEADMode,
+		}
+	}
+
+	return packetConfig, nil
+}
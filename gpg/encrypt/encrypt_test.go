@@ -1,8 +1,19 @@
 package encrypt
 
 import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgtest"
 )
 
 func Test_GPG_Encrypt_Service_Modify(t *testing.T) {
@@ -17,12 +28,12 @@ func Test_GPG_Encrypt_Service_Modify(t *testing.T) {
 	// how GPG works. Below we can only assume to have a proper GPG message by
 	// comparing its length and verify the prefix and suffix of the GPG message is
 	// as expected.
-	expected := []byte(`-----BEGIN PGP SIGNATURE-----
+	expected := []byte(`-----BEGIN PGP MESSAGE-----
 
 xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx
 xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx
 xxxxx
------END PGP SIGNATURE-----`) // "hello world"
+-----END PGP MESSAGE-----`) // "hello world"
 	value := []byte("hello world")
 	modified, err := newService.Modify(value)
 	if err != nil {
@@ -34,14 +45,117 @@ xxxxx
 	if lm != le {
 		t.Fatal("expected", le, "got", lm)
 	}
-	if !strings.HasPrefix(string(modified), "-----BEGIN PGP SIGNATURE-----\n\n") {
+	if !strings.HasPrefix(string(modified), "-----BEGIN PGP MESSAGE-----\n\n") {
 		t.Fatal("expected", true, "got", false)
 	}
-	if !strings.HasSuffix(string(modified), "\n-----END PGP SIGNATURE-----") {
+	if !strings.HasSuffix(string(modified), "\n-----END PGP MESSAGE-----") {
 		t.Fatal("expected", true, "got", false)
 	}
 }
 
+func Test_GPG_Encrypt_Service_Modify_ArmorHeaders(t *testing.T) {
+	config := DefaultConfig()
+	config.Pass = "foo"
+	config.ArmorHeaders = map[string]string{
+		"Comment": "hello",
+	}
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	modified, err := newService.Modify([]byte("hello world"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	block, err := armor.Decode(bytes.NewReader(modified))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if block.Type != MessageType {
+		t.Fatal("expected", MessageType, "got", block.Type)
+	}
+	if block.Header["Comment"] != "hello" {
+		t.Fatal("expected", "hello", "got", block.Header["Comment"])
+	}
+}
+
+func Test_GPG_Encrypt_Service_Modify_Output(t *testing.T) {
+	testCases := []struct {
+		Output string
+		Decode func(b []byte) (io.Reader, error)
+	}{
+		// Test case 1, armored GPG messages are produced.
+		{
+			Output: OutputArmored,
+			Decode: func(b []byte) (io.Reader, error) {
+				block, err := armor.Decode(bytes.NewReader(b))
+				if err != nil {
+					return nil, err
+				}
+				return block.Body, nil
+			},
+		},
+		// Test case 2, binary GPG messages are produced.
+		{
+			Output: OutputBinary,
+			Decode: func(b []byte) (io.Reader, error) {
+				return bytes.NewReader(b), nil
+			},
+		},
+		// Test case 3, compact GPG messages are produced on a single line.
+		{
+			Output: OutputCompact,
+			Decode: func(b []byte) (io.Reader, error) {
+				if bytes.ContainsAny(b, "\r\n") {
+					return nil, fmt.Errorf("compact GPG message must not contain line breaks")
+				}
+				d, err := base64.StdEncoding.DecodeString(string(b))
+				if err != nil {
+					return nil, err
+				}
+				return bytes.NewReader(d), nil
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.Pass = "foo"
+		config.Output = tc.Output
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		expected := []byte("hello world")
+		modified, err := newService.Modify(expected)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		r, err := tc.Decode(modified)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+			return []byte("foo"), nil
+		}
+		details, err := openpgp.ReadMessage(r, nil, prompt, nil)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		b, err := io.ReadAll(details.UnverifiedBody)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(b) != string(expected) {
+			t.Fatal("test", i+1, "expected", string(expected), "got", string(b))
+		}
+	}
+}
+
 func Test_GPG_Encrypt_Service_Modify_Empty(t *testing.T) {
 	config := DefaultConfig()
 	config.Pass = "foo"
@@ -61,3 +175,260 @@ func Test_GPG_Encrypt_Service_Modify_Empty(t *testing.T) {
 		t.Fatal("expected", expected, "got", modified)
 	}
 }
+
+func Test_GPG_Encrypt_Service_Modify_PublicKeys(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+	bob := gpgtest.NewEntity(t, "bob")
+
+	config := DefaultConfig()
+	config.PublicKeys = []string{gpgtest.ArmoredPublicKey(t, alice)}
+	config.KeyRing = openpgp.EntityList{bob}
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	expected := []byte("hello world")
+	modified, err := newService.Modify(expected)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Every recipient must be able to decrypt the message on its own.
+	for _, e := range []*openpgp.Entity{alice, bob} {
+		block, err := armor.Decode(bytes.NewReader(modified))
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+		details, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{e}, nil, nil)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+		b, err := io.ReadAll(details.UnverifiedBody)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+		if string(b) != string(expected) {
+			t.Fatal("expected", string(expected), "got", string(b))
+		}
+	}
+}
+
+func Test_GPG_Encrypt_New_Error(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+
+	testCases := []struct {
+		Config Config
+	}{
+		// Test case 1, neither a passphrase nor recipients are given.
+		{
+			Config: DefaultConfig(),
+		},
+		// Test case 2, a passphrase and recipients are given.
+		{
+			Config: Config{
+				Pass:    "foo",
+				KeyRing: openpgp.EntityList{alice},
+			},
+		},
+		// Test case 3, public keys are not armored.
+		{
+			Config: Config{
+				PublicKeys: []string{"foo"},
+			},
+		},
+		// Test case 4, signing requires recipients.
+		{
+			Config: Config{
+				Pass:   "foo",
+				Signer: alice,
+			},
+		},
+		// Test case 5, insecure ciphers are rejected.
+		{
+			Config: Config{
+				Pass:   "foo",
+				Cipher: packet.CipherCAST5,
+			},
+		},
+		// Test case 6, insecure S2K modes are rejected.
+		{
+			Config: Config{
+				Pass:    "foo",
+				S2KMode: s2k.SaltedS2K,
+			},
+		},
+		// Test case 7, compression levels must be valid.
+		{
+			Config: Config{
+				Pass:             "foo",
+				Compression:      packet.CompressionZLIB,
+				CompressionLevel: 10,
+			},
+		},
+		// Test case 8, unknown profiles are rejected.
+		{
+			Config: Config{
+				Pass:    "foo",
+				Profile: "rfc1991",
+			},
+		},
+		// Test case 9, armor headers must not inject further headers.
+		{
+			Config: Config{
+				Pass: "foo",
+				ArmorHeaders: map[string]string{
+					"Comment": "foo\nVersion: bar",
+				},
+			},
+		},
+		// Test case 10, armor headers require armored output.
+		{
+			Config: Config{
+				Pass:   "foo",
+				Output: OutputCompact,
+				ArmorHeaders: map[string]string{
+					"Comment": "foo",
+				},
+			},
+		},
+		// Test case 11, unknown outputs are rejected.
+		{
+			Config: Config{
+				Pass:   "foo",
+				Output: "hex",
+			},
+		},
+		// Test case 12, compression level 0 would store values uncompressed.
+		{
+			Config: Config{
+				Pass:             "foo",
+				Compression:      packet.CompressionZIP,
+				CompressionLevel: 0,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := New(tc.Config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("test", i+1, "expected", true, "got", false)
+		}
+	}
+}
+
+func Test_GPG_Encrypt_Service_Modify_PacketConfig(t *testing.T) {
+	testCases := []struct {
+		Cipher          packet.CipherFunction
+		Compression     packet.CompressionAlgo
+		S2KMode         s2k.Mode
+		Argon2          *s2k.Argon2Config
+		AEADMode        packet.AEADMode
+		Profile         string
+		ExpectedVersion int
+		ExpectedMode    packet.AEADMode
+	}{
+		// Test case 1, the configured cipher is used.
+		{
+			Cipher:          packet.CipherAES256,
+			Compression:     packet.CompressionZLIB,
+			S2KMode:         s2k.IteratedSaltedS2K,
+			ExpectedVersion: 4,
+		},
+		// Test case 2, AEAD and Argon2 are used.
+		{
+			Cipher:      packet.CipherAES192,
+			Compression: packet.CompressionNone,
+			S2KMode:     s2k.Argon2S2K,
+			Argon2: &s2k.Argon2Config{
+				NumberOfPasses:      1,
+				DegreeOfParallelism: 1,
+				Memory:              64,
+			},
+			AEADMode:        packet.AEADModeGCM,
+			ExpectedVersion: 6,
+			ExpectedMode:    packet.AEADModeGCM,
+		},
+		// Test case 3, the RFC 9580 profile defaults to AEAD and Argon2.
+		{
+			Cipher:      packet.CipherAES256,
+			Compression: packet.CompressionNone,
+			Argon2: &s2k.Argon2Config{
+				NumberOfPasses:      1,
+				DegreeOfParallelism: 1,
+				Memory:              64,
+			},
+			Profile:         ProfileRFC9580,
+			ExpectedVersion: 6,
+			ExpectedMode:    packet.AEADModeOCB,
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.Pass = "foo"
+		config.Cipher = tc.Cipher
+		config.Compression = tc.Compression
+		config.S2KMode = tc.S2KMode
+		config.Argon2 = tc.Argon2
+		config.AEADMode = tc.AEADMode
+		config.Profile = tc.Profile
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		expected := []byte("hello world")
+		modified, err := newService.Modify(expected)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		// The first packet of the message carries the configured parameters.
+		{
+			block, err := armor.Decode(bytes.NewReader(modified))
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			p, err := packet.NewReader(block.Body).Next()
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			ske, ok := p.(*packet.SymmetricKeyEncrypted)
+			if !ok {
+				t.Fatalf("test %d expected %T got %T", i+1, ske, p)
+			}
+			if ske.Version != tc.ExpectedVersion {
+				t.Fatal("test", i+1, "expected", tc.ExpectedVersion, "got", ske.Version)
+			}
+			if ske.CipherFunc != tc.Cipher {
+				t.Fatal("test", i+1, "expected", tc.Cipher, "got", ske.CipherFunc)
+			}
+			if tc.ExpectedMode != 0 && ske.Mode != tc.ExpectedMode {
+				t.Fatal("test", i+1, "expected", tc.ExpectedMode, "got", ske.Mode)
+			}
+		}
+
+		// The message must still be decryptable.
+		{
+			block, err := armor.Decode(bytes.NewReader(modified))
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+				return []byte("foo"), nil
+			}
+			details, err := openpgp.ReadMessage(block.Body, nil, prompt, nil)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			b, err := io.ReadAll(details.UnverifiedBody)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			if string(b) != string(expected) {
+				t.Fatal("test", i+1, "expected", string(expected), "got", string(b))
+			}
+		}
+	}
+}
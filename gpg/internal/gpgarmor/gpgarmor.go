@@ -0,0 +1,6 @@
+// Package gpgarmor holds the armor definitions shared by the GPG value
+// modifiers reading and writing armored GPG messages.
+package gpgarmor
+
+// MessageType is the armor block type of GPG messages.
+const MessageType = "PGP MESSAGE"
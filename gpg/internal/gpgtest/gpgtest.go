@@ -0,0 +1,93 @@
+// Package gpgtest provides fixtures shared by the tests of the GPG value
+// modifiers.
+package gpgtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// NewEntity returns a new Curve25519 entity for the given name, which is
+// cheap to generate compared to RSA keys.
+func NewEntity(t testing.TB, name string) *openpgp.Entity {
+	config := &packet.Config{
+		Algorithm: packet.PubKeyAlgoEdDSA,
+		Curve:     packet.Curve25519,
+	}
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return e
+}
+
+// ArmoredPublicKey returns the armored public key of the given entity.
+func ArmoredPublicKey(t testing.TB, e *openpgp.Entity) string {
+	buf := bytes.NewBuffer(nil)
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = e.Serialize(w)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return buf.String()
+}
+
+// ArmoredPrivateKey returns the armored private key of the given entity. The
+// private key is protected using the given passphrase, if any.
+func ArmoredPrivateKey(t testing.TB, e *openpgp.Entity, pass string) string {
+	buf := bytes.NewBuffer(nil)
+	w, err := armor.Encode(buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = e.SerializePrivate(w, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	if pass == "" {
+		return buf.String()
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(buf)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = entities[0].EncryptPrivateKeys([]byte(pass), nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	buf = bytes.NewBuffer(nil)
+	w, err = armor.Encode(buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = entities[0].SerializePrivateWithoutSigning(w, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return buf.String()
+}
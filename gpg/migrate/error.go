@@ -0,0 +1,21 @@
+package migrate
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidArmorError = &microerror.Error{
+	Kind: "invalidArmorError",
+}
+
+// IsInvalidArmor asserts invalidArmorError.
+func IsInvalidArmor(err error) bool {
+	return microerror.Cause(err) == invalidArmorError
+}
@@ -0,0 +1,141 @@
+package migrate
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgarmor"
+)
+
+// Config represents the configuration used to create a new GPG migration value
+// modifier.
+type Config struct {
+	// Settings.
+
+	// ArmorHeaders are added to the armor of every migrated GPG message,
+	// overwriting existing headers of the same name. Keys must not be empty
+	// and must not contain colons or line breaks. Values must not contain line
+	// breaks.
+	ArmorHeaders map[string]string
+}
+
+// DefaultConfig provides a default configuration to create a new GPG
+// migration value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		ArmorHeaders: nil,
+	}
+}
+
+// New creates a new configured GPG migration value modifier.
+func New(config Config) (*Service, error) {
+	// Settings.
+	for k, v := range config.ArmorHeaders {
+		if k == "" || strings.ContainsAny(k, ":\r\n") || strings.ContainsAny(v, "\r\n") {
+			return nil, microerror.Maskf(invalidConfigError, "config.ArmorHeaders must not contain header %q", k)
+		}
+	}
+
+	newService := &Service{
+		armorHeaders: config.ArmorHeaders,
+	}
+
+	return newService, nil
+}
+
+// Service implements the GPG migration value modifier.
+type Service struct {
+	// Settings.
+	armorHeaders map[string]string
+}
+
+// Modify rewrites GPG messages which older versions of the GPG encryption value
+// modifier armored as "PGP SIGNATURE" to use the "PGP MESSAGE" armor. The
+// encrypted message itself is not touched, so no keys or passphrases are
+// required. Values already armored as "PGP MESSAGE" are returned as they are,
+// unless armor headers are configured. Actual signatures are returned as they
+// are as well.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	block, err := armor.Decode(bytes.NewReader(value))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	switch block.Type {
+	case gpgarmor.MessageType:
+		if len(s.armorHeaders) == 0 {
+			return value, nil
+		}
+	case openpgp.SignatureType:
+	default:
+		return nil, microerror.Maskf(invalidArmorError, "GPG message must not be armored as %q", block.Type)
+	}
+
+	message, err := io.ReadAll(block.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if block.Type == openpgp.SignatureType {
+		encrypted, err := isEncrypted(message)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !encrypted {
+			return value, nil
+		}
+	}
+
+	headers := map[string]string{}
+	for k, v := range block.Header {
+		headers[k] = v
+	}
+	for k, v := range s.armorHeaders {
+		headers[k] = v
+	}
+
+	buf := bytes.NewBuffer(nil)
+	encoder, err := armor.Encode(buf, gpgarmor.MessageType, headers)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	_, err = encoder.Write(message)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isEncrypted reports whether the given binary OpenPGP message starts with an
+// encrypted session key, which is the case for every encrypted GPG message.
+func isEncrypted(message []byte) (bool, error) {
+	p, err := packet.NewReader(bytes.NewReader(message)).Next()
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	switch p.(type) {
+	case *packet.SymmetricKeyEncrypted, *packet.EncryptedKey:
+		return true, nil
+	}
+
+	return false, nil
+}
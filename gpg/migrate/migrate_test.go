@@ -0,0 +1,182 @@
+package migrate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+
+	"github.com/giantswarm/valuemodifier/gpg/decrypt"
+	"github.com/giantswarm/valuemodifier/gpg/encrypt"
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgtest"
+)
+
+// legacyMessage is "hello world" encrypted with the passphrase "foo" by an
+// older version of gpg/encrypt, which armored GPG messages as signatures.
+const legacyMessage = `-----BEGIN PGP SIGNATURE-----
+
+wx4EBwMItflyy+CkVHfgNJ9CwJz0SXR8DVmT+GrIQpbSPAFfOlMN/2J8XF2/hRCm
+oHm+HyYpiGLqnC/rncq3SRJ9z0xSEbhS5l+Dp3xMTGniaNEU2xtt72M35kS+HA==
+=R5GK
+-----END PGP SIGNATURE-----`
+
+func Test_GPG_Migrate_Service_Modify(t *testing.T) {
+	testCases := []struct {
+		ArmorHeaders   map[string]string
+		Value          func(t *testing.T) []byte
+		ExpectedPrefix string
+		Unchanged      bool
+	}{
+		// Test case 1, GPG messages armored as signatures are rewritten.
+		{
+			Value: func(t *testing.T) []byte {
+				return []byte(legacyMessage)
+			},
+			ExpectedPrefix: "-----BEGIN PGP MESSAGE-----\n\n",
+		},
+		// Test case 2, armor headers are added while rewriting.
+		{
+			ArmorHeaders: map[string]string{
+				"Comment": "migrated",
+			},
+			Value: func(t *testing.T) []byte {
+				return []byte(legacyMessage)
+			},
+			ExpectedPrefix: "-----BEGIN PGP MESSAGE-----\nComment: migrated\n\n",
+		},
+		// Test case 3, GPG messages already armored as messages are left
+		// untouched.
+		{
+			Value: func(t *testing.T) []byte {
+				return encryptedMessage(t)
+			},
+			Unchanged: true,
+		},
+		// Test case 4, actual signatures are left untouched.
+		{
+			Value: func(t *testing.T) []byte {
+				return detachedSignature(t)
+			},
+			Unchanged: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.ArmorHeaders = tc.ArmorHeaders
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		value := tc.Value(t)
+		modified, err := newService.Modify(value)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		if tc.Unchanged {
+			if string(modified) != string(value) {
+				t.Fatal("test", i+1, "expected", string(value), "got", string(modified))
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(string(modified), tc.ExpectedPrefix) {
+			t.Fatal("test", i+1, "expected", tc.ExpectedPrefix, "got", string(modified))
+		}
+
+		// Migrating must be idempotent.
+		{
+			remodified, err := newService.Modify(modified)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			if string(remodified) != string(modified) {
+				t.Fatal("test", i+1, "expected", string(modified), "got", string(remodified))
+			}
+		}
+
+		// The migrated message must still be decryptable.
+		{
+			config := decrypt.DefaultConfig()
+			config.Pass = "foo"
+			decryptService, err := decrypt.New(config)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			b, err := decryptService.Modify(modified)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			if string(b) != "hello world" {
+				t.Fatal("test", i+1, "expected", "hello world", "got", string(b))
+			}
+		}
+	}
+}
+
+func Test_GPG_Migrate_Service_Modify_Error(t *testing.T) {
+	config := DefaultConfig()
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = gpgtest.NewEntity(t, "alice").Serialize(w)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	_, err = newService.Modify(buf.Bytes())
+	if !IsInvalidArmor(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func Test_GPG_Migrate_New_Error(t *testing.T) {
+	config := DefaultConfig()
+	config.ArmorHeaders = map[string]string{
+		"Comment: injected": "foo",
+	}
+	_, err := New(config)
+	if !IsInvalidConfig(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func encryptedMessage(t *testing.T) []byte {
+	config := encrypt.DefaultConfig()
+	config.Pass = "foo"
+	newService, err := encrypt.New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	b, err := newService.Modify([]byte("hello world"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return b
+}
+
+func detachedSignature(t *testing.T) []byte {
+	buf := bytes.NewBuffer(nil)
+	err := openpgp.ArmoredDetachSign(buf, gpgtest.NewEntity(t, "alice"), strings.NewReader("hello world"), nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return buf.Bytes()
+}
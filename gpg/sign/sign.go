@@ -0,0 +1,131 @@
+package sign
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgarmor"
+)
+
+// Config represents the configuration used to create a new GPG signing value
+// modifier.
+type Config struct {
+	// Settings.
+
+	// PrivateKey is the armored private key used to sign GPG messages. It must
+	// not be used together with Signer.
+	PrivateKey string
+	// KeyPass is the passphrase used to unlock a passphrase protected private
+	// key of PrivateKey or Signer.
+	KeyPass string
+	// Signer is the entity used to sign GPG messages. It must not be used
+	// together with PrivateKey.
+	Signer *openpgp.Entity
+}
+
+// DefaultConfig provides a default configuration to create a new GPG signing
+// value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		PrivateKey: "",
+		KeyPass:    "",
+		Signer:     nil,
+	}
+}
+
+// New creates a new configured GPG signing value modifier.
+func New(config Config) (*Service, error) {
+	// Settings.
+	if config.PrivateKey == "" && config.Signer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.PrivateKey or config.Signer must not be empty")
+	}
+	if config.PrivateKey != "" && config.Signer != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.PrivateKey must be empty when config.Signer provided")
+	}
+
+	signer := config.Signer
+	if config.PrivateKey != "" {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(config.PrivateKey))
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.PrivateKey must be an armored private key: %s", err.Error())
+		}
+		if len(entities) != 1 {
+			return nil, microerror.Maskf(invalidConfigError, "config.PrivateKey must contain exactly one key")
+		}
+		signer = entities[0]
+	}
+
+	{
+		if signer.PrivateKey == nil {
+			return nil, microerror.Maskf(invalidConfigError, "signer %X must be a private key", signer.PrimaryKey.Fingerprint)
+		}
+		key, ok := signer.SigningKey(time.Now())
+		if !ok {
+			return nil, microerror.Maskf(invalidConfigError, "signer %X must have a valid signing key", signer.PrimaryKey.Fingerprint)
+		}
+		if key.PrivateKey.Encrypted {
+			if config.KeyPass == "" {
+				return nil, microerror.Maskf(invalidConfigError, "config.KeyPass must not be empty when signer %X is passphrase protected", signer.PrimaryKey.Fingerprint)
+			}
+			err := signer.DecryptPrivateKeys([]byte(config.KeyPass))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.KeyPass must unlock signer %X: %s", signer.PrimaryKey.Fingerprint, err.Error())
+			}
+		}
+	}
+
+	newService := &Service{
+		signer: signer,
+	}
+
+	return newService, nil
+}
+
+// Service implements the GPG signing value modifier.
+type Service struct {
+	// Settings.
+	signer *openpgp.Entity
+}
+
+// Modify returns the given value wrapped into an armored GPG message signed by
+// the configured signer. The value is not encrypted. Use the GPG verification
+// value modifier to verify the signature and unwrap the value again.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	encoder, err := armor.Encode(buf, gpgarmor.MessageType, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	signer, err := openpgp.Sign(encoder, s.signer, nil, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	_, err = signer.Write(value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = signer.Close()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package sign
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgtest"
+)
+
+func Test_GPG_Sign_Service_Modify(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+
+	config := DefaultConfig()
+	config.Signer = alice
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	expected := []byte("hello world")
+	modified, err := newService.Modify(expected)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	if !strings.HasPrefix(string(modified), "-----BEGIN PGP MESSAGE-----\n") {
+		t.Fatal("expected", true, "got", false)
+	}
+
+	block, err := armor.Decode(bytes.NewReader(modified))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	details, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{alice}, nil, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	b, err := io.ReadAll(details.UnverifiedBody)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(b) != string(expected) {
+		t.Fatal("expected", string(expected), "got", string(b))
+	}
+	if details.SignedBy == nil || details.SignedBy.Entity != alice {
+		t.Fatal("expected", alice, "got", details.SignedBy)
+	}
+	if details.SignatureError != nil {
+		t.Fatal("expected", nil, "got", details.SignatureError)
+	}
+}
+
+func Test_GPG_Sign_Service_Modify_Empty(t *testing.T) {
+	config := DefaultConfig()
+	config.Signer = gpgtest.NewEntity(t, "alice")
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	expected := []byte("")
+	value := []byte("")
+	modified, err := newService.Modify(value)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	if string(modified) != string(expected) {
+		t.Fatal("expected", expected, "got", modified)
+	}
+}
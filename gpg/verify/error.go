@@ -0,0 +1,39 @@
+package verify
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidSignatureError = &microerror.Error{
+	Kind: "invalidSignatureError",
+}
+
+// IsInvalidSignature asserts invalidSignatureError.
+func IsInvalidSignature(err error) bool {
+	return microerror.Cause(err) == invalidSignatureError
+}
+
+var missingSignatureError = &microerror.Error{
+	Kind: "missingSignatureError",
+}
+
+// IsMissingSignature asserts missingSignatureError.
+func IsMissingSignature(err error) bool {
+	return microerror.Cause(err) == missingSignatureError
+}
+
+var unknownSignerError = &microerror.Error{
+	Kind: "unknownSignerError",
+}
+
+// IsUnknownSigner asserts unknownSignerError.
+func IsUnknownSigner(err error) bool {
+	return microerror.Cause(err) == unknownSignerError
+}
@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/giantswarm/microerror"
+)
+
+// Config represents the configuration used to create a new GPG verification
+// value modifier.
+type Config struct {
+	// Settings.
+
+	// PublicKeys are the armored public keys of the trusted signers. Each item
+	// may contain multiple keys.
+	PublicKeys []string
+	// KeyRing holds trusted signers, in addition to the ones defined by
+	// PublicKeys.
+	KeyRing openpgp.EntityList
+}
+
+// DefaultConfig provides a default configuration to create a new GPG
+// verification value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		PublicKeys: nil,
+		KeyRing:    nil,
+	}
+}
+
+// New creates a new configured GPG verification value modifier.
+func New(config Config) (*Service, error) {
+	var keyRing openpgp.EntityList
+	{
+		keyRing = append(keyRing, config.KeyRing...)
+
+		for _, k := range config.PublicKeys {
+			entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(k))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "config.PublicKeys must only contain armored public keys: %s", err.Error())
+			}
+			keyRing = append(keyRing, entities...)
+		}
+	}
+
+	// Settings.
+	if len(keyRing) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.PublicKeys or config.KeyRing must not be empty")
+	}
+
+	newService := &Service{
+		keyRing: keyRing,
+	}
+
+	return newService, nil
+}
+
+// Service implements the GPG verification value modifier.
+type Service struct {
+	// Settings.
+	keyRing openpgp.EntityList
+}
+
+// Modify verifies the signature of the given armored GPG message, as created
+// by the GPG signing value modifier, and returns the signed value. It fails
+// when the message is not signed, when it is signed by an unknown signer or
+// when the signature is invalid.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	buf := bytes.NewBuffer(value)
+	decoder, err := armor.Decode(buf)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	details, err := openpgp.ReadMessage(decoder.Body, s.keyRing, nil, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The signature can only be checked after the whole body was read.
+	b, err := io.ReadAll(details.UnverifiedBody)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if !details.IsSigned {
+		return nil, microerror.Maskf(missingSignatureError, "GPG message must be signed")
+	}
+	if details.SignedBy == nil {
+		return nil, microerror.Maskf(unknownSignerError, "GPG message signed by unknown key %X", details.SignedByKeyId)
+	}
+	if details.SignatureError != nil {
+		return nil, microerror.Maskf(invalidSignatureError, "%s", details.SignatureError.Error())
+	}
+
+	return b, nil
+}
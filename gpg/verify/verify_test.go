@@ -0,0 +1,110 @@
+package verify
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgarmor"
+	"github.com/giantswarm/valuemodifier/gpg/internal/gpgtest"
+	"github.com/giantswarm/valuemodifier/gpg/sign"
+)
+
+func Test_GPG_Verify_Service_Modify(t *testing.T) {
+	alice := gpgtest.NewEntity(t, "alice")
+	bob := gpgtest.NewEntity(t, "bob")
+
+	expected := []byte("hello world")
+
+	testCases := []struct {
+		Signer       *openpgp.Entity
+		KeyRing      openpgp.EntityList
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, a message signed by a trusted signer is verified.
+		{
+			Signer:  alice,
+			KeyRing: openpgp.EntityList{bob, alice},
+		},
+		// Test case 2, a message signed by an unknown signer is rejected.
+		{
+			Signer:       alice,
+			KeyRing:      openpgp.EntityList{bob},
+			ErrorMatcher: IsUnknownSigner,
+		},
+		// Test case 3, a message which is not signed is rejected.
+		{
+			Signer:       nil,
+			KeyRing:      openpgp.EntityList{alice},
+			ErrorMatcher: IsMissingSignature,
+		},
+	}
+
+	for i, tc := range testCases {
+		var value []byte
+		if tc.Signer != nil {
+			config := sign.DefaultConfig()
+			config.Signer = tc.Signer
+			signService, err := sign.New(config)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+			value, err = signService.Modify(expected)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+		} else {
+			value = unsignedMessage(t, expected)
+		}
+
+		config := DefaultConfig()
+		config.KeyRing = tc.KeyRing
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := newService.Modify(value)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != string(expected) {
+			t.Fatal("test", i+1, "expected", string(expected), "got", string(modified))
+		}
+	}
+}
+
+func unsignedMessage(t *testing.T, value []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	encoder, err := armor.Encode(buf, gpgarmor.MessageType, nil)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	w, err := packet.SerializeLiteral(encoder, true, "", 0)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	_, err = w.Write(value)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return buf.Bytes()
+}
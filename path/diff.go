@@ -0,0 +1,168 @@
+package path
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cast"
+)
+
+const (
+	// ChangeTypeAdded marks paths only found in the second document.
+	ChangeTypeAdded = "added"
+	// ChangeTypeRemoved marks paths only found in the first document.
+	ChangeTypeRemoved = "removed"
+	// ChangeTypeChanged marks paths found in both documents with different
+	// values.
+	ChangeTypeChanged = "changed"
+)
+
+// Change describes the difference of a single path between two documents.
+// Values are deliberately not part of a change, so that diffs of encrypted
+// documents do not leak any plain text.
+type Change struct {
+	Path string
+	Type string
+}
+
+// ValueModifier is the same as valuemodifier.ValueModifier, which cannot be
+// used here without causing an import cycle.
+type ValueModifier interface {
+	Modify(value []byte) ([]byte, error)
+}
+
+// PathValueModifier is the same as valuemodifier.PathValueModifier, which
+// cannot be used here without causing an import cycle.
+type PathValueModifier interface {
+	ValueModifier
+	ModifyPath(path string, value []byte) ([]byte, error)
+}
+
+// Diff compares the leaves of the given JSON or YAML documents and returns the
+// paths which were added, removed or changed, sorted by path. Other than All
+// and Walk, Diff also compares the leaves of embedded documents found inside
+// objects. When value modifiers are given, differing string values are
+// compared after applying the value modifiers to both of them. This way e.g. a
+// decrypting value modifier can be used to report values as unchanged, which
+// were re-encrypted without changing their plain text. Value modifiers
+// implementing PathValueModifier are called using ModifyPath with the path of
+// the compared values.
+func Diff(a, b []byte, valueModifiers ...ValueModifier) ([]Change, error) {
+	leavesA, err := diffLeaves(a)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	leavesB, err := diffLeaves(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var changes []Change
+
+	for p, la := range leavesA {
+		lb, ok := leavesB[p]
+		if !ok {
+			changes = append(changes, Change{Path: p, Type: ChangeTypeRemoved})
+			continue
+		}
+
+		equal, err := equalLeaves(p, la, lb, valueModifiers)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !equal {
+			changes = append(changes, Change{Path: p, Type: ChangeTypeChanged})
+		}
+	}
+
+	for p := range leavesB {
+		_, ok := leavesA[p]
+		if !ok {
+			changes = append(changes, Change{Path: p, Type: ChangeTypeAdded})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+type leaf struct {
+	Value interface{}
+	Kind  LeafKind
+}
+
+func diffLeaves(input []byte) (map[string]leaf, error) {
+	var err error
+
+	var newService *Service
+	{
+		config := DefaultConfig()
+		config.InputBytes = input
+		newService, err = New(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	leaves := map[string]leaf{}
+	{
+		c := walkConfig{
+			embeddedInLists:   true,
+			embeddedInObjects: true,
+		}
+		walkFunc := func(path string, value interface{}, kind LeafKind) error {
+			leaves[path] = leaf{Value: value, Kind: kind}
+			return nil
+		}
+
+		err := newService.walkWithConfig(c, walkFunc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return leaves, nil
+}
+
+func equalLeaves(path string, a, b leaf, valueModifiers []ValueModifier) (bool, error) {
+	if a.Kind == b.Kind && reflect.DeepEqual(a.Value, b.Value) {
+		return true, nil
+	}
+	if a.Kind != LeafKindString || b.Kind != LeafKindString || len(valueModifiers) == 0 {
+		return false, nil
+	}
+
+	va, err := modifyValue(path, cast.ToString(a.Value), valueModifiers)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	vb, err := modifyValue(path, cast.ToString(b.Value), valueModifiers)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return va == vb, nil
+}
+
+func modifyValue(path string, value string, valueModifiers []ValueModifier) (string, error) {
+	var err error
+
+	b := []byte(value)
+	for _, m := range valueModifiers {
+		pm, ok := m.(PathValueModifier)
+		if ok {
+			b, err = pm.ModifyPath(path, b)
+		} else {
+			b, err = m.Modify(b)
+		}
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+	}
+
+	return string(b), nil
+}
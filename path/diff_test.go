@@ -0,0 +1,146 @@
+package path
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+)
+
+type testDecrypter struct{}
+
+// Modify strips the random suffix of values "encrypted" as "<value>|<salt>".
+func (m testDecrypter) Modify(value []byte) ([]byte, error) {
+	return []byte(strings.Split(string(value), "|")[0]), nil
+}
+
+var testPathError = &microerror.Error{
+	Kind: "testPathError",
+}
+
+type testPathDecrypter struct{}
+
+// Modify fails, since values are bound to their path.
+func (m testPathDecrypter) Modify(value []byte) ([]byte, error) {
+	return nil, microerror.Maskf(testPathError, "path required")
+}
+
+// ModifyPath strips the random suffix and path of values "encrypted" as
+// "<value>|<salt>|<path>", failing for values bound to another path.
+func (m testPathDecrypter) ModifyPath(path string, value []byte) ([]byte, error) {
+	parts := strings.Split(string(value), "|")
+	if len(parts) != 3 || parts[2] != path {
+		return nil, microerror.Maskf(testPathError, "value must be bound to path %q", path)
+	}
+
+	return []byte(parts[0]), nil
+}
+
+func Test_Diff(t *testing.T) {
+	testCases := []struct {
+		A              []byte
+		B              []byte
+		ValueModifiers []ValueModifier
+		Expected       []Change
+	}{
+		// Test case 1, equal documents have no changes, regardless of their
+		// format.
+		{
+			A: []byte(`{
+  "k1": "v1",
+  "k2": [1, 2]
+}`),
+			B: []byte(`k1: v1
+k2:
+- 1
+- 2
+`),
+			Expected: nil,
+		},
+
+		// Test case 2, added, removed and changed paths are reported.
+		{
+			A: []byte(`{
+  "k1": "v1",
+  "k2": {
+    "k3": "v3"
+  },
+  "k4": 4
+}`),
+			B: []byte(`{
+  "k1": "modified",
+  "k2": {
+    "k5": "v5"
+  },
+  "k4": "4"
+}`),
+			Expected: []Change{
+				{Path: "k1", Type: ChangeTypeChanged},
+				{Path: "k2.k3", Type: ChangeTypeRemoved},
+				{Path: "k2.k5", Type: ChangeTypeAdded},
+				{Path: "k4", Type: ChangeTypeChanged},
+			},
+		},
+
+		// Test case 3, leaves of embedded documents are compared.
+		{
+			A: []byte(`k1: |
+  k2: v2
+  k3: v3
+`),
+			B: []byte(`k1: |
+  k2: v2
+  k3: modified
+`),
+			Expected: []Change{
+				{Path: "k1.k3", Type: ChangeTypeChanged},
+			},
+		},
+
+		// Test case 4, values are compared through the given value modifiers.
+		{
+			A: []byte(`k1: v1|salt1
+k2: v2|salt2
+`),
+			B: []byte(`k1: v1|salt3
+k2: modified|salt4
+`),
+			ValueModifiers: []ValueModifier{
+				testDecrypter{},
+			},
+			Expected: []Change{
+				{Path: "k2", Type: ChangeTypeChanged},
+			},
+		},
+
+		// Test case 5, values are compared through value modifiers binding
+		// values to their path.
+		{
+			A: []byte(`k1: v1|salt1|k1
+k2:
+  k3: v3|salt2|k2.k3
+`),
+			B: []byte(`k1: v1|salt3|k1
+k2:
+  k3: modified|salt4|k2.k3
+`),
+			ValueModifiers: []ValueModifier{
+				testPathDecrypter{},
+			},
+			Expected: []Change{
+				{Path: "k2.k3", Type: ChangeTypeChanged},
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		changes, err := Diff(tc.A, tc.B, tc.ValueModifiers...)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(tc.Expected, changes) {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", changes)
+		}
+	}
+}
@@ -37,3 +37,21 @@ var notFoundError = &microerror.Error{
 func IsNotFound(err error) bool {
 	return microerror.Cause(err) == notFoundError
 }
+
+var invalidPatchError = &microerror.Error{
+	Kind: "invalidPatchError",
+}
+
+// IsInvalidPatch asserts invalidPatchError.
+func IsInvalidPatch(err error) bool {
+	return microerror.Cause(err) == invalidPatchError
+}
+
+var testFailedError = &microerror.Error{
+	Kind: "testFailedError",
+}
+
+// IsTestFailed asserts testFailedError.
+func IsTestFailed(err error) bool {
+	return microerror.Cause(err) == testFailedError
+}
@@ -0,0 +1,189 @@
+package path
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	yamltojson "github.com/ghodss/yaml"
+	"github.com/giantswarm/microerror"
+)
+
+// Flatten returns all leaves of the given JSON or YAML document keyed by their
+// path, using the given separator. Other than All, Flatten also returns null
+// values inside lists and never inspects strings for embedded documents, so
+// that the result can be turned back into the very same document using
+// Unflatten. Strings are returned as they are, unless they would be mistaken
+// for another type, e.g. "true" or "123", in which case they are returned
+// quoted as JSON strings. All other values are returned JSON encoded. Numbers
+// are returned as they are written in the given document, so that integers
+// beyond 2^53 keep their precision.
+func Flatten(input []byte, separator string) (map[string]string, error) {
+	var err error
+
+	var newService *Service
+	{
+		config := DefaultConfig()
+		config.InputBytes = input
+		config.Separator = separator
+		newService, err = New(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		// New decodes numbers as float64, which cannot represent all integers
+		// exactly. The structure is decoded again keeping numbers as they are.
+		newService.jsonStructure, err = decodeNumbers(input)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	flat := map[string]string{}
+	{
+		c := walkConfig{
+			nullsInLists: true,
+		}
+		walkFunc := func(path string, value interface{}, kind LeafKind) error {
+			s, ok := value.(string)
+			if ok && !json.Valid([]byte(s)) {
+				flat[path] = s
+				return nil
+			}
+
+			b, err := json.Marshal(value)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			flat[path] = string(b)
+
+			return nil
+		}
+
+		err := newService.walkWithConfig(c, walkFunc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return flat, nil
+}
+
+// Unflatten is the reverse of Flatten. It builds a JSON document from the
+// given leaves keyed by their path, using the given separator. Values which
+// are valid JSON are decoded to restore their original type. Lists must not
+// have gaps, that is, all indices of a list must be defined.
+func Unflatten(flat map[string]string, separator string) ([]byte, error) {
+	var err error
+
+	var newService *Service
+	{
+		config := DefaultConfig()
+		config.InputBytes = []byte("{}")
+		config.Separator = separator
+		newService, err = New(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	type flatPath struct {
+		Key   string
+		Split []string
+	}
+
+	var paths []flatPath
+	for k := range flat {
+		paths = append(paths, flatPath{Key: k, Split: strings.Split(newService.escapeKey(k), separator)})
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return lessPath(paths[i].Split, paths[j].Split)
+	})
+
+	var jsonStructure interface{}
+	for _, p := range paths {
+		var value interface{}
+		{
+			s := flat[p.Key]
+			if json.Valid([]byte(s)) {
+				value, err = unmarshalNumbers([]byte(s))
+				if err != nil {
+					return nil, microerror.Mask(err)
+				}
+			} else {
+				value = s
+			}
+		}
+
+		jsonStructure, err = newService.setFromInterface(strings.Join(p.Split, separator), value, jsonStructure)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if jsonStructure == nil {
+		jsonStructure = map[string]interface{}{}
+	}
+
+	err = newService.setStructure(jsonStructure)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newService.jsonBytes, nil
+}
+
+// lessPath orders split paths key by key, where list indices are ordered
+// numerically. This ensures lists are built in index order.
+func lessPath(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+
+		ia, errA := indexFromKey(a[i])
+		ib, errB := indexFromKey(b[i])
+		if errA == nil && errB == nil {
+			return ia < ib
+		}
+
+		return a[i] < b[i]
+	}
+
+	return len(a) < len(b)
+}
+
+// decodeNumbers decodes the given JSON or YAML document like New, but keeps
+// numbers as json.Number.
+func decodeNumbers(b []byte) (interface{}, error) {
+	if !isJSON(b) {
+		var err error
+		b, err = yamltojson.YAMLToJSON(b)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	v, err := unmarshalNumbers(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return v, nil
+}
+
+// unmarshalNumbers decodes the given JSON value, keeping numbers as
+// json.Number.
+func unmarshalNumbers(b []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,210 @@
+package path
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Flatten(t *testing.T) {
+	testCases := []struct {
+		InputBytes []byte
+		Separator  string
+		Expected   map[string]string
+	}{
+		// Test case 1, ensure values of all types are flattened.
+		{
+			InputBytes: []byte(`{
+  "k1": {
+    "k2": "v2",
+    "k3": 3,
+    "k4": true,
+    "k5": null,
+    "k6": {},
+    "k7": []
+  },
+  "k8": [
+    "v8",
+    null,
+    {
+      "k9": "v9"
+    }
+  ]
+}`),
+			Separator: ".",
+			Expected: map[string]string{
+				"k1.k2":     "v2",
+				"k1.k3":     "3",
+				"k1.k4":     "true",
+				"k1.k5":     "null",
+				"k1.k6":     "{}",
+				"k1.k7":     "[]",
+				"k8.[0]":    "v8",
+				"k8.[1]":    "null",
+				"k8.[2].k9": "v9",
+			},
+		},
+
+		// Test case 2, ensure strings which look like other types are quoted and
+		// embedded documents are kept as they are.
+		{
+			InputBytes: []byte(`k1: "true"
+k2: "123"
+k3: '"v3"'
+k4:
+- |
+  k5: v5
+`),
+			Separator: ".",
+			Expected: map[string]string{
+				"k1":     `"true"`,
+				"k2":     `"123"`,
+				"k3":     `"\"v3\""`,
+				"k4.[0]": "k5: v5\n",
+			},
+		},
+
+		// Test case 3, ensure custom separators and escaped keys are used.
+		{
+			InputBytes: []byte(`{
+  "k1": {
+    "k2_k3": "v3"
+  }
+}`),
+			Separator: "_",
+			Expected: map[string]string{
+				`k1_k2\_k3`: "v3",
+			},
+		},
+
+		// Test case 4, ensure integers beyond 2^53 keep their precision.
+		{
+			InputBytes: []byte(`{
+  "k1": 9007199254740993,
+  "k2": [
+    18446744073709551615,
+    1.5
+  ]
+}`),
+			Separator: ".",
+			Expected: map[string]string{
+				"k1":     "9007199254740993",
+				"k2.[0]": "18446744073709551615",
+				"k2.[1]": "1.5",
+			},
+		},
+
+		// Test case 5, ensure integers of YAML documents beyond 2^53 keep their
+		// precision.
+		{
+			InputBytes: []byte(`k1: 9007199254740993
+k2:
+- 18446744073709551615
+`),
+			Separator: ".",
+			Expected: map[string]string{
+				"k1":     "9007199254740993",
+				"k2.[0]": "18446744073709551615",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		flat, err := Flatten(tc.InputBytes, tc.Separator)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(tc.Expected, flat) {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", flat)
+		}
+
+		// Ensure the flattened document round-trips.
+		output, err := Unflatten(flat, tc.Separator)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		reflattened, err := Flatten(output, tc.Separator)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(flat, reflattened) {
+			t.Fatal("test", i+1, "expected", flat, "got", reflattened)
+		}
+	}
+}
+
+func Test_Unflatten(t *testing.T) {
+	testCases := []struct {
+		Flat     map[string]string
+		Expected []byte
+	}{
+		// Test case 1, ensure lists are built in index order and types are
+		// restored.
+		{
+			Flat: map[string]string{
+				"k1.[10]":   "v10",
+				"k1.[2]":    "v2",
+				"k1.[0].k2": "2",
+				"k1.[1]":    `"1"`,
+				"k1.[3]":    "v3",
+				"k1.[4]":    "v4",
+				"k1.[5]":    "v5",
+				"k1.[6]":    "v6",
+				"k1.[7]":    "v7",
+				"k1.[8]":    "v8",
+				"k1.[9]":    "null",
+			},
+			Expected: []byte(`{
+  "k1": [
+    {
+      "k2": 2
+    },
+    "1",
+    "v2",
+    "v3",
+    "v4",
+    "v5",
+    "v6",
+    "v7",
+    "v8",
+    null,
+    "v10"
+  ]
+}`),
+		},
+
+		// Test case 2, ensure top level lists are built.
+		{
+			Flat: map[string]string{
+				"[0]": "v0",
+				"[1]": "false",
+			},
+			Expected: []byte(`[
+  "v0",
+  false
+]`),
+		},
+	}
+
+	for i, tc := range testCases {
+		output, err := Unflatten(tc.Flat, ".")
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(tc.Expected, output) {
+			t.Fatal("test", i+1, "expected", string(tc.Expected), "got", string(output))
+		}
+	}
+}
+
+func Test_Unflatten_Error(t *testing.T) {
+	// Lists must not have gaps.
+	flat := map[string]string{
+		"k1.[0]": "v0",
+		"k1.[2]": "v2",
+	}
+
+	_, err := Unflatten(flat, ".")
+	if !IsNotFound(err) {
+		t.Fatal("expected", true, "got", false)
+	}
+}
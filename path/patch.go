@@ -0,0 +1,545 @@
+package path
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	patchOpAdd     = "add"
+	patchOpCopy    = "copy"
+	patchOpMove    = "move"
+	patchOpRemove  = "remove"
+	patchOpReplace = "replace"
+	patchOpTest    = "test"
+)
+
+// PatchOperation represents a single operation of a RFC 6902 JSON Patch
+// document.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The value is only rendered for
+// operations which define one, so that it can be null when needed.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	operation := map[string]interface{}{
+		"op":   o.Op,
+		"path": o.Path,
+	}
+	if o.From != "" {
+		operation["from"] = o.From
+	}
+	switch o.Op {
+	case patchOpAdd, patchOpReplace, patchOpTest:
+		operation["value"] = o.Value
+	}
+
+	b, err := json.Marshal(operation)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+// ApplyPatch applies the given RFC 6902 JSON Patch document to the configured
+// structure. The patch can be provided in JSON or YAML format. Operations are
+// applied atomically, that is, when any operation fails the configured
+// structure is left untouched. Note that JSON Pointers address the structure as
+// it is, which means embedded JSON or YAML documents are treated as strings.
+func (s *Service) ApplyPatch(patch []byte) error {
+	var operations []PatchOperation
+	{
+		jsonBytes, _, err := toJSON(patch)
+		if err != nil {
+			return microerror.Maskf(invalidPatchError, "patch must be a JSON or YAML list")
+		}
+
+		// The operations are decoded twice. The first pass validates the overall
+		// shape of the patch. The second pass tells apart operations with a
+		// missing value from operations with an explicit null value, which is a
+		// valid value for add, replace and test.
+		err = json.Unmarshal(jsonBytes, &operations)
+		if err != nil {
+			return microerror.Maskf(invalidPatchError, "%s", err.Error())
+		}
+
+		var raw []map[string]json.RawMessage
+		err = json.Unmarshal(jsonBytes, &raw)
+		if err != nil {
+			return microerror.Maskf(invalidPatchError, "%s", err.Error())
+		}
+
+		for i, o := range operations {
+			switch o.Op {
+			case patchOpAdd, patchOpReplace, patchOpTest:
+				_, ok := raw[i]["value"]
+				if !ok {
+					return microerror.Maskf(invalidPatchError, "operation %d (%s) must define value", i, o.Op)
+				}
+			case patchOpCopy, patchOpMove:
+				_, ok := raw[i]["from"]
+				if !ok {
+					return microerror.Maskf(invalidPatchError, "operation %d (%s) must define from", i, o.Op)
+				}
+			case patchOpRemove:
+				// Nothing to validate beyond the path.
+			default:
+				return microerror.Maskf(invalidPatchError, "operation %d has unknown op '%s'", i, o.Op)
+			}
+		}
+	}
+
+	document, err := deepCopy(s.jsonStructure)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, o := range operations {
+		document, err = applyPatchOperation(document, o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = s.setStructure(document)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// ApplyMergePatch applies the given RFC 7386 JSON Merge Patch document to the
+// configured structure. The patch can be provided in JSON or YAML format.
+func (s *Service) ApplyMergePatch(patch []byte) error {
+	var patchStructure interface{}
+	{
+		jsonBytes, _, err := toJSON(patch)
+		if err != nil {
+			// A merge patch which is not an object replaces the whole document.
+			// Such patches are scalars, which are not recognized as documents.
+			jsonBytes = patch
+		}
+
+		err = json.Unmarshal(jsonBytes, &patchStructure)
+		if err != nil {
+			return microerror.Maskf(invalidPatchError, "%s", err.Error())
+		}
+	}
+
+	document, err := deepCopy(s.jsonStructure)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = s.setStructure(mergePatch(document, patchStructure))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Patch returns RFC 6902 replace operations which set the given paths to the
+// values currently found in the configured structure. Embedded JSON or YAML
+// documents cannot be addressed by JSON Pointers, which is why paths pointing
+// into embedded documents result in operations replacing the whole embedded
+// document. Operations are de-duplicated and sorted by their JSON Pointer.
+func (s *Service) Patch(paths []string) ([]PatchOperation, error) {
+	var pointers []string
+	values := map[string]interface{}{}
+
+	for _, p := range paths {
+		tokens, err := s.pointerFromPath(p)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		pointer := formatPointer(tokens)
+		if _, ok := values[pointer]; ok {
+			continue
+		}
+
+		value, err := pointerGet(s.jsonStructure, tokens)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		pointers = append(pointers, pointer)
+		values[pointer] = value
+	}
+
+	sort.Strings(pointers)
+
+	var operations []PatchOperation
+	for _, p := range pointers {
+		operations = append(operations, PatchOperation{
+			Op:    patchOpReplace,
+			Path:  p,
+			Value: values[p],
+		})
+	}
+
+	return operations, nil
+}
+
+// pointerFromPath translates the given path to the reference tokens of a JSON
+// Pointer. Translation stops at the first embedded document, if any.
+func (s *Service) pointerFromPath(path string) ([]string, error) {
+	var tokens []string
+
+	current := s.jsonStructure
+	for _, k := range strings.Split(s.escapeKey(path), s.separator) {
+		key := s.unescapeKey(k)
+
+		switch c := current.(type) {
+		case map[string]interface{}:
+			v, ok := c[key]
+			if !ok {
+				return nil, microerror.Maskf(notFoundError, "key '%s'", path)
+			}
+			tokens = append(tokens, key)
+			current = v
+		case []interface{}:
+			i, err := indexFromKey(key)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			if i >= len(c) {
+				return nil, microerror.Maskf(notFoundError, "key '%s'", path)
+			}
+			tokens = append(tokens, strconv.Itoa(i))
+			current = c[i]
+		default:
+			return tokens, nil
+		}
+	}
+
+	return tokens, nil
+}
+
+// setStructure replaces the configured structure and keeps the rendered JSON
+// bytes in sync with it.
+func (s *Service) setStructure(jsonStructure interface{}) error {
+	b, err := json.MarshalIndent(jsonStructure, "", "  ")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	s.jsonStructure = jsonStructure
+	s.jsonBytes = b
+
+	return nil
+}
+
+func applyPatchOperation(document interface{}, o PatchOperation) (interface{}, error) {
+	tokens, err := parsePointer(o.Path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	switch o.Op {
+	case patchOpAdd:
+		value, err := deepCopy(o.Value)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return pointerAdd(document, tokens, value)
+
+	case patchOpRemove:
+		document, _, err = pointerRemove(document, tokens)
+		return document, err
+
+	case patchOpReplace:
+		value, err := deepCopy(o.Value)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		document, _, err = pointerRemove(document, tokens)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return pointerAdd(document, tokens, value)
+
+	case patchOpMove:
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if isPointerPrefix(from, tokens) && len(from) != len(tokens) {
+			return nil, microerror.Maskf(invalidPatchError, "cannot move '%s' into one of its children '%s'", o.From, o.Path)
+		}
+		var value interface{}
+		document, value, err = pointerRemove(document, from)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return pointerAdd(document, tokens, value)
+
+	case patchOpCopy:
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		value, err := pointerGet(document, from)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		value, err = deepCopy(value)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return pointerAdd(document, tokens, value)
+
+	case patchOpTest:
+		value, err := pointerGet(document, tokens)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !reflect.DeepEqual(value, o.Value) {
+			return nil, microerror.Maskf(testFailedError, "value at '%s' does not match", o.Path)
+		}
+		return document, nil
+	}
+
+	return nil, microerror.Maskf(invalidPatchError, "unknown op '%s'", o.Op)
+}
+
+// mergePatch implements the MergePatch function as defined in RFC 7386.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = map[string]interface{}{}
+	}
+
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+		} else {
+			targetMap[k] = mergePatch(targetMap[k], v)
+		}
+	}
+
+	return targetMap
+}
+
+// parsePointer splits the given RFC 6901 JSON Pointer into its unescaped
+// reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, microerror.Maskf(invalidPatchError, "pointer '%s' must start with '/'", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		t = strings.ReplaceAll(t, "~1", "/")
+		t = strings.ReplaceAll(t, "~0", "~")
+		tokens[i] = t
+	}
+
+	return tokens, nil
+}
+
+// formatPointer joins the given reference tokens to a RFC 6901 JSON Pointer.
+func formatPointer(tokens []string) string {
+	var pointer string
+	for _, t := range tokens {
+		t = strings.ReplaceAll(t, "~", "~0")
+		t = strings.ReplaceAll(t, "/", "~1")
+		pointer += "/" + t
+	}
+
+	return pointer
+}
+
+func isPointerPrefix(prefix []string, tokens []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pointerIndex parses the given reference token as array index. The index may
+// be equal to the length of the array when allowAppend is true, in which case
+// the token "-" is accepted as well.
+func pointerIndex(token string, length int, allowAppend bool) (int, error) {
+	if allowAppend && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, microerror.Maskf(invalidPatchError, "invalid array index '%s'", token)
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, microerror.Maskf(invalidPatchError, "invalid array index '%s'", token)
+	}
+
+	max := length - 1
+	if allowAppend {
+		max = length
+	}
+	if i > max {
+		return 0, microerror.Maskf(notFoundError, "array index '%s'", token)
+	}
+
+	return i, nil
+}
+
+func pointerGet(document interface{}, tokens []string) (interface{}, error) {
+	current := document
+	for _, t := range tokens {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			v, ok := c[t]
+			if !ok {
+				return nil, microerror.Maskf(notFoundError, "key '%s'", t)
+			}
+			current = v
+		case []interface{}:
+			i, err := pointerIndex(t, len(c), false)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			current = c[i]
+		default:
+			return nil, microerror.Maskf(notFoundError, "key '%s'", t)
+		}
+	}
+
+	return current, nil
+}
+
+func pointerAdd(document interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	t := tokens[0]
+
+	switch c := document.(type) {
+	case map[string]interface{}:
+		if len(tokens) == 1 {
+			c[t] = value
+			return c, nil
+		}
+		child, ok := c[t]
+		if !ok {
+			return nil, microerror.Maskf(notFoundError, "key '%s'", t)
+		}
+		modified, err := pointerAdd(child, tokens[1:], value)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		c[t] = modified
+		return c, nil
+
+	case []interface{}:
+		if len(tokens) == 1 {
+			i, err := pointerIndex(t, len(c), true)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		i, err := pointerIndex(t, len(c), false)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		modified, err := pointerAdd(c[i], tokens[1:], value)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		c[i] = modified
+		return c, nil
+	}
+
+	return nil, microerror.Maskf(notFoundError, "key '%s'", t)
+}
+
+func pointerRemove(document interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, document, nil
+	}
+
+	t := tokens[0]
+
+	switch c := document.(type) {
+	case map[string]interface{}:
+		child, ok := c[t]
+		if !ok {
+			return nil, nil, microerror.Maskf(notFoundError, "key '%s'", t)
+		}
+		if len(tokens) == 1 {
+			delete(c, t)
+			return c, child, nil
+		}
+		modified, removed, err := pointerRemove(child, tokens[1:])
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		c[t] = modified
+		return c, removed, nil
+
+	case []interface{}:
+		i, err := pointerIndex(t, len(c), false)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		if len(tokens) == 1 {
+			removed := c[i]
+			c = append(c[:i], c[i+1:]...)
+			return c, removed, nil
+		}
+		modified, removed, err := pointerRemove(c[i], tokens[1:])
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		c[i] = modified
+		return c, removed, nil
+	}
+
+	return nil, nil, microerror.Maskf(notFoundError, "key '%s'", t)
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var copied interface{}
+	err = json.Unmarshal(b, &copied)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return copied, nil
+}
@@ -0,0 +1,275 @@
+package path
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Service_ApplyPatch(t *testing.T) {
+	testCases := []struct {
+		InputBytes []byte
+		Patch      []byte
+		Expected   []byte
+	}{
+		// Test case 1, ensure values can be replaced.
+		{
+			InputBytes: []byte(`{
+  "k1": "v1",
+  "k2": "v2"
+}`),
+			Patch: []byte(`[
+  {"op": "replace", "path": "/k1", "value": "modified"}
+]`),
+			Expected: []byte(`{
+  "k1": "modified",
+  "k2": "v2"
+}`),
+		},
+
+		// Test case 2, ensure values can be added and removed in nested objects
+		// and lists.
+		{
+			InputBytes: []byte(`{
+  "k1": {
+    "k2": [
+      "v1",
+      "v3"
+    ]
+  },
+  "k3": "v3"
+}`),
+			Patch: []byte(`[
+  {"op": "add", "path": "/k1/k2/1", "value": "v2"},
+  {"op": "add", "path": "/k1/k2/-", "value": "v4"},
+  {"op": "remove", "path": "/k3"}
+]`),
+			Expected: []byte(`{
+  "k1": {
+    "k2": [
+      "v1",
+      "v2",
+      "v3",
+      "v4"
+    ]
+  }
+}`),
+		},
+
+		// Test case 3, ensure values can be moved and copied.
+		{
+			InputBytes: []byte(`{
+  "k1": {
+    "k2": "v2"
+  }
+}`),
+			Patch: []byte(`[
+  {"op": "copy", "from": "/k1", "path": "/k3"},
+  {"op": "move", "from": "/k1/k2", "path": "/k4"}
+]`),
+			Expected: []byte(`{
+  "k1": {},
+  "k3": {
+    "k2": "v2"
+  },
+  "k4": "v2"
+}`),
+		},
+
+		// Test case 4, ensure escaped pointers and successful tests are handled.
+		{
+			InputBytes: []byte(`{
+  "k1/k2": "v1",
+  "k3~k4": "v2"
+}`),
+			Patch: []byte(`[
+  {"op": "test", "path": "/k1~1k2", "value": "v1"},
+  {"op": "replace", "path": "/k3~0k4", "value": null}
+]`),
+			Expected: []byte(`{
+  "k1/k2": "v1",
+  "k3~k4": null
+}`),
+		},
+
+		// Test case 5, ensure YAML input is written as YAML and YAML patches are
+		// accepted.
+		{
+			InputBytes: []byte(`k1:
+  k2: v2
+`),
+			Patch: []byte(`- op: add
+  path: /k1/k3
+  value: v3
+`),
+			Expected: []byte(`k1:
+  k2: v2
+  k3: v3
+`),
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.InputBytes = tc.InputBytes
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		err = newService.ApplyPatch(tc.Patch)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		output, err := newService.OutputBytes()
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(tc.Expected, output) {
+			t.Fatal("test", i+1, "expected", string(tc.Expected), "got", string(output))
+		}
+	}
+}
+
+func Test_Service_ApplyPatch_Error(t *testing.T) {
+	testCases := []struct {
+		InputBytes   []byte
+		Patch        []byte
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, replacing a missing key fails.
+		{
+			InputBytes:   []byte(`{"k1": "v1"}`),
+			Patch:        []byte(`[{"op": "replace", "path": "/k2", "value": "v2"}]`),
+			ErrorMatcher: IsNotFound,
+		},
+
+		// Test case 2, a failing test operation aborts the patch.
+		{
+			InputBytes:   []byte(`{"k1": "v1"}`),
+			Patch:        []byte(`[{"op": "add", "path": "/k2", "value": "v2"}, {"op": "test", "path": "/k1", "value": "v2"}]`),
+			ErrorMatcher: IsTestFailed,
+		},
+
+		// Test case 3, unknown operations are rejected.
+		{
+			InputBytes:   []byte(`{"k1": "v1"}`),
+			Patch:        []byte(`[{"op": "unknown", "path": "/k1"}]`),
+			ErrorMatcher: IsInvalidPatch,
+		},
+
+		// Test case 4, add operations must define a value.
+		{
+			InputBytes:   []byte(`{"k1": "v1"}`),
+			Patch:        []byte(`[{"op": "add", "path": "/k2"}]`),
+			ErrorMatcher: IsInvalidPatch,
+		},
+
+		// Test case 5, list indices must be within bounds.
+		{
+			InputBytes:   []byte(`{"k1": ["v1"]}`),
+			Patch:        []byte(`[{"op": "add", "path": "/k1/2", "value": "v2"}]`),
+			ErrorMatcher: IsNotFound,
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.InputBytes = tc.InputBytes
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		err = newService.ApplyPatch(tc.Patch)
+		if !tc.ErrorMatcher(err) {
+			t.Fatal("test", i+1, "expected", true, "got", false)
+		}
+
+		// Failed patches must not modify the structure.
+		output, err := newService.OutputBytes()
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(tc.InputBytes, output) {
+			t.Fatal("test", i+1, "expected", string(tc.InputBytes), "got", string(output))
+		}
+	}
+}
+
+func Test_Service_ApplyMergePatch(t *testing.T) {
+	testCases := []struct {
+		InputBytes []byte
+		Patch      []byte
+		Expected   []byte
+	}{
+		// Test case 1, ensure values are merged, replaced and removed.
+		{
+			InputBytes: []byte(`{
+  "k1": {
+    "k2": "v2",
+    "k3": "v3"
+  },
+  "k4": [
+    "v4"
+  ]
+}`),
+			Patch: []byte(`{
+  "k1": {
+    "k2": null,
+    "k5": "v5"
+  },
+  "k4": [
+    "v6"
+  ]
+}`),
+			Expected: []byte(`{
+  "k1": {
+    "k3": "v3",
+    "k5": "v5"
+  },
+  "k4": [
+    "v6"
+  ]
+}`),
+		},
+
+		// Test case 2, ensure YAML input is written as YAML and YAML patches are
+		// accepted.
+		{
+			InputBytes: []byte(`k1:
+  k2: v2
+k3: v3
+`),
+			Patch: []byte(`k1:
+  k2: modified
+k3: null
+`),
+			Expected: []byte(`k1:
+  k2: modified
+`),
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.InputBytes = tc.InputBytes
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		err = newService.ApplyMergePatch(tc.Patch)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		output, err := newService.OutputBytes()
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(tc.Expected, output) {
+			t.Fatal("test", i+1, "expected", string(tc.Expected), "got", string(output))
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
@@ -100,7 +99,12 @@ type Service struct {
 
 // All returns all paths found in the configured JSON structure.
 func (s *Service) All() ([]string, error) {
-	paths, err := s.allFromInterface(s.jsonStructure)
+	var paths []string
+
+	err := s.Walk(func(path string, value interface{}, kind LeafKind) error {
+		paths = append(paths, path)
+		return nil
+	})
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
@@ -135,18 +139,15 @@ func (s *Service) OutputBytes() ([]byte, error) {
 
 // Set changes the value of the given path.
 func (s *Service) Set(path string, value interface{}) error {
-	var err error
-
-	s.jsonStructure, err = s.setFromInterface(s.escapeKey(path), value, s.jsonStructure)
+	jsonStructure, err := s.setFromInterface(s.escapeKey(path), value, s.jsonStructure)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	b, err := json.MarshalIndent(s.jsonStructure, "", "  ")
+	err = s.setStructure(jsonStructure)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	s.jsonBytes = b
 
 	return nil
 }
@@ -178,121 +179,6 @@ func (s *Service) Validate(paths []string) error {
 	return nil
 }
 
-func (s *Service) allFromInterface(value interface{}) ([]string, error) {
-	if value == nil {
-		return nil, nil
-	}
-
-	// process map
-	{
-		stringMap, err := cast.ToStringMapE(value)
-		if err != nil {
-			// fall through
-		} else {
-			var paths []string
-
-			for k, v := range stringMap {
-				if v == nil {
-					paths = append(paths, k)
-					continue
-				}
-
-				var ps []string
-				if reflect.TypeOf(v).String() != "string" {
-					ps, err = s.allFromInterface(v)
-					if err != nil {
-						return nil, microerror.Mask(err)
-					}
-				}
-
-				k := s.separatorExpression.ReplaceAllString(k, fmt.Sprintf(`\%s`, s.separator))
-
-				if ps != nil { // nolint:gosimple
-					for _, p := range ps {
-						paths = append(paths, fmt.Sprintf("%s%s%s", k, s.separator, p))
-					}
-				} else {
-					paths = append(paths, k)
-				}
-			}
-
-			return paths, nil
-		}
-	}
-
-	// process slice
-	{
-		slice, err := cast.ToSliceE(value)
-		if err != nil {
-			// fall through
-		} else {
-			var paths []string
-
-			for i, v := range slice {
-				// A consequence of this is, when `null` is part of the slice it is
-				// not going to be returned as a path, which is ok, because `null` is
-				// not something that we must necessarily process. We could drop the `null`
-				// alltogether, but this would require changing the original object,
-				// which does not seem necessary.
-				if v == nil {
-					continue
-				}
-				ps, err := s.allFromInterface(v)
-				if err != nil {
-					return nil, microerror.Mask(err)
-				}
-
-				// Since strings processing, see `process string`, expects objects as slice
-				// elements, it returns and empty result otherwise, i.e. when given slice element
-				// is "usual" data type. If we leave it like this, we get the whole slince under
-				// the path.
-				// Instead of processing a slice as a whole, let's add its elements as standalone
-				// paths.
-				if ps == nil {
-					paths = append(paths, fmt.Sprintf("[%d]", i))
-					continue
-				}
-
-				for _, p := range ps {
-					paths = append(paths, fmt.Sprintf("[%d]%s%s", i, s.separator, p))
-				}
-			}
-
-			return paths, nil
-		}
-	}
-
-	// process string
-	{
-		str, err := cast.ToStringE(value)
-		if err != nil {
-			// fall through
-		} else if str == "" {
-			// fall through
-		} else {
-			jsonBytes, _, err := toJSON([]byte(str))
-			if err != nil {
-				// fall through
-			} else {
-				var jsonStructure interface{}
-				err := json.Unmarshal(jsonBytes, &jsonStructure)
-				if err != nil {
-					return nil, microerror.Mask(err)
-				}
-
-				ps, err := s.allFromInterface(jsonStructure)
-				if err != nil {
-					return nil, microerror.Mask(err)
-				}
-
-				return ps, nil
-			}
-		}
-	}
-
-	return nil, nil
-}
-
 func (s *Service) escapeKey(key string) string {
 	return s.escapedSeparatorExpression.ReplaceAllString(key, escapedSeparatorPlaceholder)
 }
@@ -0,0 +1,217 @@
+package path
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+)
+
+// LeafKind describes the type of a leaf value visited by Walk.
+type LeafKind int
+
+const (
+	// LeafKindString is the kind of string values.
+	LeafKindString LeafKind = iota
+	// LeafKindNumber is the kind of numeric values.
+	LeafKindNumber
+	// LeafKindBool is the kind of boolean values.
+	LeafKindBool
+	// LeafKindNull is the kind of null values.
+	LeafKindNull
+	// LeafKindObject is the kind of objects without any leaves, e.g. empty
+	// objects.
+	LeafKindObject
+	// LeafKindList is the kind of lists without any leaves, e.g. empty lists.
+	LeafKindList
+)
+
+// String returns the human readable name of the leaf kind.
+func (k LeafKind) String() string {
+	switch k {
+	case LeafKindString:
+		return "string"
+	case LeafKindNumber:
+		return "number"
+	case LeafKindBool:
+		return "bool"
+	case LeafKindNull:
+		return "null"
+	case LeafKindObject:
+		return "object"
+	case LeafKindList:
+		return "list"
+	}
+
+	return fmt.Sprintf("LeafKind(%d)", int(k))
+}
+
+// SkipSubtree can be returned by a WalkFunc to skip the remaining leaves of the
+// object or list containing the currently visited leaf. Walk does not return
+// SkipSubtree as error.
+var SkipSubtree = &microerror.Error{
+	Kind: "skipSubtree",
+}
+
+// WalkFunc is called by Walk for every visited leaf. The given path can be
+// used with Get and Set.
+type WalkFunc func(path string, value interface{}, kind LeafKind) error
+
+// Walk visits every leaf of the configured structure exactly once. The visited
+// paths are the same as the ones returned by All. Object keys are visited in
+// sorted order and list elements in index order. Strings inside objects are
+// always leaves, while strings inside lists are inspected for embedded
+// documents. Leaves of embedded documents are visited with their already
+// parsed values, so callers do not need to use Get.
+func (s *Service) Walk(walkFunc WalkFunc) error {
+	err := s.walkWithConfig(walkConfig{embeddedInLists: true}, walkFunc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// walkConfig defines how the leaves of a structure are found.
+type walkConfig struct {
+	// embeddedInLists defines whether strings inside lists are inspected for
+	// embedded documents.
+	embeddedInLists bool
+	// embeddedInObjects defines whether strings inside objects are inspected
+	// for embedded documents.
+	embeddedInObjects bool
+	// nullsInLists defines whether null values inside lists are visited.
+	nullsInLists bool
+}
+
+func (s *Service) walkWithConfig(config walkConfig, walkFunc WalkFunc) error {
+	_, err := s.walk(config, "", s.jsonStructure, walkFunc)
+	if err != nil && !isSkipSubtree(err) {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// walk visits the leaves of the given value using the given path prefix. It
+// returns whether any leaf was found.
+func (s *Service) walk(config walkConfig, prefix string, value interface{}, walkFunc WalkFunc) (bool, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p := s.joinPath(prefix, s.separatorExpression.ReplaceAllString(k, fmt.Sprintf(`\%s`, s.separator)))
+
+			if _, ok := v[k].(string); !ok || config.embeddedInObjects {
+				found, err := s.walk(config, p, v[k], walkFunc)
+				if err != nil {
+					return true, microerror.Mask(err)
+				}
+				if found {
+					continue
+				}
+			}
+
+			err := walkFunc(p, v[k], leafKind(v[k]))
+			if isSkipSubtree(err) {
+				return true, nil
+			} else if err != nil {
+				return true, microerror.Mask(err)
+			}
+		}
+
+		return len(keys) != 0, nil
+
+	case []interface{}:
+		var found bool
+
+		for i, e := range v {
+			// Null values inside lists are not visited by default. See also All.
+			if e == nil && !config.nullsInLists {
+				continue
+			}
+			found = true
+
+			p := s.joinPath(prefix, fmt.Sprintf("[%d]", i))
+
+			if _, ok := e.(string); !ok || config.embeddedInLists {
+				f, err := s.walk(config, p, e, walkFunc)
+				if err != nil {
+					return true, microerror.Mask(err)
+				}
+				if f {
+					continue
+				}
+			}
+
+			err := walkFunc(p, e, leafKind(e))
+			if isSkipSubtree(err) {
+				return true, nil
+			} else if err != nil {
+				return true, microerror.Mask(err)
+			}
+		}
+
+		return found, nil
+
+	case string:
+		// Strings may carry embedded JSON or YAML documents, whose leaves are
+		// visited as well.
+		if v == "" {
+			return false, nil
+		}
+		jsonBytes, _, err := toJSON([]byte(v))
+		if err != nil {
+			return false, nil
+		}
+		var jsonStructure interface{}
+		err = json.Unmarshal(jsonBytes, &jsonStructure)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		found, err := s.walk(config, prefix, jsonStructure, walkFunc)
+		if err != nil {
+			return found, microerror.Mask(err)
+		}
+
+		return found, nil
+	}
+
+	return false, nil
+}
+
+func (s *Service) joinPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + s.separator + key
+}
+
+func isSkipSubtree(err error) bool {
+	return microerror.Cause(err) == SkipSubtree
+}
+
+func leafKind(value interface{}) LeafKind {
+	switch value.(type) {
+	case string:
+		return LeafKindString
+	case float64, json.Number:
+		return LeafKindNumber
+	case bool:
+		return LeafKindBool
+	case map[string]interface{}:
+		return LeafKindObject
+	case []interface{}:
+		return LeafKindList
+	}
+
+	return LeafKindNull
+}
@@ -0,0 +1,142 @@
+package path
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+)
+
+func Test_Service_Walk(t *testing.T) {
+	testCases := []struct {
+		InputBytes []byte
+		Skip       string
+		Expected   []string
+	}{
+		// Test case 1, ensure leaves are visited in order with their values and
+		// kinds.
+		{
+			InputBytes: []byte(`{
+  "k3": "v3",
+  "k1": {
+    "k2": 1
+  },
+  "k4": [
+    true,
+    null,
+    "v5"
+  ],
+  "k6": null,
+  "k7": {},
+  "k8": []
+}`),
+			Expected: []string{
+				"k1.k2=1 (number)",
+				"k3=v3 (string)",
+				"k4.[0]=true (bool)",
+				"k4.[2]=v5 (string)",
+				"k6=<nil> (null)",
+				"k7=map[] (object)",
+				"k8=[] (list)",
+			},
+		},
+
+		// Test case 2, ensure leaves of embedded documents inside lists are
+		// visited with their parsed values.
+		{
+			InputBytes: []byte(`k1:
+- |
+  k2: v2
+  k3:
+  - v3
+`),
+			Expected: []string{
+				"k1.[0].k2=v2 (string)",
+				"k1.[0].k3.[0]=v3 (string)",
+			},
+		},
+
+		// Test case 3, ensure the remaining leaves of an object are skipped.
+		{
+			InputBytes: []byte(`{
+  "k1": {
+    "k2": "v2",
+    "k3": "v3",
+    "k4": "v4"
+  },
+  "k5": "v5"
+}`),
+			Skip: "k1.k3",
+			Expected: []string{
+				"k1.k2=v2 (string)",
+				"k1.k3=v3 (string)",
+				"k5=v5 (string)",
+			},
+		},
+
+		// Test case 4, ensure the remaining leaves of a list are skipped.
+		{
+			InputBytes: []byte(`k1:
+- v1
+- v2
+- k2: v3
+k4: v4
+`),
+			Skip: "k1.[0]",
+			Expected: []string{
+				"k1.[0]=v1 (string)",
+				"k4=v4 (string)",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.InputBytes = tc.InputBytes
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		var visited []string
+		err = newService.Walk(func(path string, value interface{}, kind LeafKind) error {
+			visited = append(visited, fmt.Sprintf("%s=%v (%s)", path, value, kind))
+			if path == tc.Skip {
+				return SkipSubtree
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !reflect.DeepEqual(tc.Expected, visited) {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", visited)
+		}
+	}
+}
+
+func Test_Service_Walk_Error(t *testing.T) {
+	testError := &microerror.Error{
+		Kind: "testError",
+	}
+
+	config := DefaultConfig()
+	config.InputBytes = []byte(`{"k1": {"k2": "v2"}, "k3": "v3"}`)
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	var visited int
+	err = newService.Walk(func(path string, value interface{}, kind LeafKind) error {
+		visited++
+		return microerror.Mask(testError)
+	})
+	if microerror.Cause(err) != testError {
+		t.Fatal("expected", testError, "got", err)
+	}
+	if visited != 1 {
+		t.Fatal("expected", 1, "got", visited)
+	}
+}
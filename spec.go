@@ -7,3 +7,24 @@ package valuemodifier
 type ValueModifier interface {
 	Modify(value []byte) ([]byte, error)
 }
+
+// PathValueModifier is a ValueModifier which additionally takes the path of
+// the value being modified into account, e.g. to bind encrypted values to their
+// location within a document. Traverse and Rotate call ModifyPath instead of
+// Modify for value modifiers implementing PathValueModifier.
+type PathValueModifier interface {
+	ValueModifier
+	ModifyPath(path string, value []byte) ([]byte, error)
+}
+
+// TraversalValueModifier is a ValueModifier which keeps state for the duration
+// of a single traversal, e.g. to share an expensive key between all values of a
+// document. Traverse and Rotate call BeginTraversal before modifying the first
+// value of a document and EndTraversal after modifying the last one, also when
+// the traversal failed. Value modifiers implementing TraversalValueModifier
+// must not be used by concurrent traversals.
+type TraversalValueModifier interface {
+	ValueModifier
+	BeginTraversal() error
+	EndTraversal()
+}
@@ -1,6 +1,7 @@
 package valuemodifier
 
 import (
+	"encoding/json"
 	"sort"
 	"strings"
 
@@ -10,15 +11,30 @@ import (
 	"github.com/giantswarm/valuemodifier/path"
 )
 
+const (
+	// OutputDocument makes Traverse return the whole modified document in the
+	// format it was provided in.
+	OutputDocument = "document"
+	// OutputJSONPatch makes Traverse return a RFC 6902 JSON Patch document
+	// replacing exactly the values changed by the configured value modifiers.
+	OutputJSONPatch = "jsonpatch"
+)
+
 // Config represents the configuration used to create a new value modifier
 // traverser.
 type Config struct {
 	// Dependencies.
+
+	// ValueModifiers are applied by Traverse. They may be empty when the
+	// value modifier traverser is only used to Rotate values.
 	ValueModifiers []ValueModifier
 
 	// Settings.
 	IgnoreFields []string
 	SelectFields []string
+	// Output defines what Traverse returns. It is either OutputDocument or
+	// OutputJSONPatch.
+	Output string
 }
 
 // DefaultConfig provides a default configuration to create a new value modifier
@@ -31,20 +47,23 @@ func DefaultConfig() Config {
 		// Settings.
 		IgnoreFields: nil,
 		SelectFields: nil,
+		Output:       OutputDocument,
 	}
 }
 
 // New creates a new configured value modifier traverser.
 func New(config Config) (*Service, error) {
-	// Dependencies.
-	if len(config.ValueModifiers) == 0 {
-		return nil, microerror.Maskf(invalidConfigError, "config.ValueModifiers must not be empty")
-	}
-
 	// Settings.
 	if len(config.IgnoreFields) != 0 && len(config.SelectFields) != 0 {
 		return nil, microerror.Maskf(invalidConfigError, "config.IgnoreFields must be empty when config.SelectFields provided")
 	}
+	switch config.Output {
+	case "", OutputDocument, OutputJSONPatch:
+		// An empty output falls back to OutputDocument to keep configurations
+		// working which do not use DefaultConfig.
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Output must be one of %q or %q", OutputDocument, OutputJSONPatch)
+	}
 
 	newService := &Service{
 		// Dependencies.
@@ -53,6 +72,7 @@ func New(config Config) (*Service, error) {
 		// Settings.
 		ignoreFields: config.IgnoreFields,
 		selectFields: config.SelectFields,
+		output:       config.Output,
 	}
 
 	return newService, nil
@@ -66,9 +86,116 @@ type Service struct {
 	// Settings.
 	ignoreFields []string
 	selectFields []string
+	output       string
 }
 
+// Traverse applies the configured value modifiers to all selected values of
+// the given JSON or YAML document. Depending on the configured output it
+// returns the modified document or a JSON Patch describing the changes.
 func (s *Service) Traverse(input []byte) ([]byte, error) {
+	if len(s.valueModifiers) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.ValueModifiers must not be empty")
+	}
+
+	pathService, values, err := s.selectValues(input)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	end, err := beginTraversal(s.valueModifiers)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer end()
+
+	var changed []string
+	for _, pv := range values {
+		original := cast.ToString(pv.Value)
+		b, err := modifyValue(pv.Path, []byte(original), s.valueModifiers)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		err = pathService.Set(pv.Path, string(b))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if string(b) != original {
+			changed = append(changed, pv.Path)
+		}
+	}
+
+	b, err := s.outputBytes(pathService, changed)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+// Rotate re-encrypts all selected values of the given JSON or YAML document.
+// Each value is modified by the old value modifiers, e.g. to decrypt it using
+// a previous key, and the result is immediately modified by the new value
+// modifiers, e.g. to encrypt it using the current key. This way plain text only
+// ever exists for a single value at a time. The configured value modifiers are
+// not used by Rotate, so that they may be empty. Depending on the configured output, Rotate returns the
+// rotated document or a JSON Patch describing the changes, together with the
+// sorted paths of all rotated values.
+func (s *Service) Rotate(input []byte, oldValueModifiers, newValueModifiers []ValueModifier) ([]byte, []string, error) {
+	if len(oldValueModifiers) == 0 {
+		return nil, nil, microerror.Maskf(invalidConfigError, "old value modifiers must not be empty")
+	}
+	if len(newValueModifiers) == 0 {
+		return nil, nil, microerror.Maskf(invalidConfigError, "new value modifiers must not be empty")
+	}
+
+	pathService, values, err := s.selectValues(input)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	end, err := beginTraversal(append(append([]ValueModifier{}, oldValueModifiers...), newValueModifiers...))
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	defer end()
+
+	var rotated []string
+	for _, pv := range values {
+		original := cast.ToString(pv.Value)
+		b, err := modifyValue(pv.Path, []byte(original), oldValueModifiers)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		b, err = modifyValue(pv.Path, b, newValueModifiers)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+
+		err = pathService.Set(pv.Path, string(b))
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+
+		if string(b) != original {
+			rotated = append(rotated, pv.Path)
+		}
+	}
+
+	b, err := s.outputBytes(pathService, rotated)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	sort.Strings(rotated)
+
+	return b, rotated, nil
+}
+
+// selectValues parses the given JSON or YAML document and returns the values
+// selected by the configured fields.
+func (s *Service) selectValues(input []byte) (*path.Service, []pathValue, error) {
 	var err error
 
 	var pathService *path.Service
@@ -77,7 +204,7 @@ func (s *Service) Traverse(input []byte) ([]byte, error) {
 		pathConfig.InputBytes = input
 		pathService, err = path.New(pathConfig)
 		if err != nil {
-			return nil, microerror.Mask(err)
+			return nil, nil, microerror.Mask(err)
 		}
 	}
 
@@ -88,54 +215,60 @@ func (s *Service) Traverse(input []byte) ([]byte, error) {
 
 		err := pathService.Validate(fields)
 		if err != nil {
-			return nil, microerror.Mask(err)
+			return nil, nil, microerror.Mask(err)
 		}
 	}
 
-	var paths []string
-	{
-		paths, err = pathService.All()
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-
-		if len(s.ignoreFields) != 0 {
-			var newPaths []string
+	var values []pathValue
+	if len(s.selectFields) != 0 {
+		paths := append([]string{}, s.selectFields...)
+		sort.Strings(paths)
 
-			for _, p := range paths {
-				pv := strings.Split(p, ".")
-				if containsString(s.ignoreFields, pv[len(pv)-1]) {
-					continue
-				}
-				newPaths = append(newPaths, p)
+		for _, p := range paths {
+			v, err := pathService.Get(p)
+			if err != nil {
+				return nil, nil, microerror.Mask(err)
 			}
+			values = append(values, pathValue{Path: p, Value: v})
+		}
+	} else {
+		walkFunc := func(p string, v interface{}, kind path.LeafKind) error {
+			pv := strings.Split(p, ".")
+			if containsString(s.ignoreFields, pv[len(pv)-1]) {
+				return nil
+			}
+			values = append(values, pathValue{Path: p, Value: v})
 
-			paths = newPaths
-		} else if len(s.selectFields) != 0 {
-			paths = s.selectFields
+			return nil
 		}
 
-		sort.Strings(paths)
+		err := pathService.Walk(walkFunc)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
 	}
 
-	for _, p := range paths {
-		v, err := pathService.Get(p)
+	return pathService, values, nil
+}
+
+// outputBytes returns the modified document or, depending on the configured
+// output, a JSON Patch replacing the given changed paths.
+func (s *Service) outputBytes(pathService *path.Service, changed []string) ([]byte, error) {
+	if s.output == OutputJSONPatch {
+		operations, err := pathService.Patch(changed)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
-
-		b := []byte(cast.ToString(v))
-		for _, m := range s.valueModifiers {
-			b, err = m.Modify(b)
-			if err != nil {
-				return nil, microerror.Mask(err)
-			}
+		if operations == nil {
+			operations = []path.PatchOperation{}
 		}
 
-		err = pathService.Set(p, string(b))
+		b, err := json.MarshalIndent(operations, "", "  ")
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
+
+		return b, nil
 	}
 
 	b, err := pathService.OutputBytes()
@@ -146,6 +279,12 @@ func (s *Service) Traverse(input []byte) ([]byte, error) {
 	return b, nil
 }
 
+// pathValue is a value found in a traversed document together with its path.
+type pathValue struct {
+	Path  string
+	Value interface{}
+}
+
 func containsString(list []string, item string) bool {
 	for _, l := range list {
 		if l == item {
@@ -155,3 +294,49 @@ func containsString(list []string, item string) bool {
 
 	return false
 }
+
+// beginTraversal begins a traversal for all of the given value modifiers
+// implementing TraversalValueModifier. The returned function ends the traversal
+// again for all value modifiers which successfully began it.
+func beginTraversal(valueModifiers []ValueModifier) (func(), error) {
+	var begun []TraversalValueModifier
+	end := func() {
+		for i := len(begun) - 1; i >= 0; i-- {
+			begun[i].EndTraversal()
+		}
+	}
+
+	for _, m := range valueModifiers {
+		tm, ok := m.(TraversalValueModifier)
+		if !ok {
+			continue
+		}
+
+		err := tm.BeginTraversal()
+		if err != nil {
+			end()
+			return nil, microerror.Mask(err)
+		}
+		begun = append(begun, tm)
+	}
+
+	return end, nil
+}
+
+func modifyValue(path string, value []byte, valueModifiers []ValueModifier) ([]byte, error) {
+	var err error
+
+	for _, m := range valueModifiers {
+		pm, ok := m.(PathValueModifier)
+		if ok {
+			value, err = pm.ModifyPath(path, value)
+		} else {
+			value, err = m.Modify(value)
+		}
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return value, nil
+}
@@ -2,8 +2,12 @@ package valuemodifier
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/giantswarm/microerror"
 )
 
 type testModifier1 struct{}
@@ -618,3 +622,362 @@ pass2: pass2
 		})
 	}
 }
+
+type testModifierNoop struct{}
+
+func (m testModifierNoop) Modify(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+func Test_ValueModifier_Traverse_JSONPatch(t *testing.T) {
+	testCases := []struct {
+		ValueModifiers []ValueModifier
+		SelectFields   []string
+		Input          string
+		Expected       string
+	}{
+		// Test case 0, changed values of nested objects and lists are replaced.
+		{
+			ValueModifiers: []ValueModifier{
+				testModifier1{},
+			},
+			SelectFields: []string{},
+			Input: `k1:
+  k2: v2
+k3:
+- v3
+- k4/k5: v5
+`,
+			Expected: `[
+  {
+    "op": "replace",
+    "path": "/k1/k2",
+    "value": "v2-modified1"
+  },
+  {
+    "op": "replace",
+    "path": "/k3/0",
+    "value": "v3-modified1"
+  },
+  {
+    "op": "replace",
+    "path": "/k3/1/k4~1k5",
+    "value": "v5-modified1"
+  }
+]`,
+		},
+
+		// Test case 1, only selected fields are part of the patch.
+		{
+			ValueModifiers: []ValueModifier{
+				testModifier1{},
+			},
+			SelectFields: []string{
+				"k1",
+			},
+			Input: `{
+  "k1": "v1",
+  "k2": "v2"
+}`,
+			Expected: `[
+  {
+    "op": "replace",
+    "path": "/k1",
+    "value": "v1-modified1"
+  }
+]`,
+		},
+
+		// Test case 2, changes inside embedded documents replace the whole
+		// embedded document.
+		{
+			ValueModifiers: []ValueModifier{
+				testModifier1{},
+			},
+			SelectFields: []string{},
+			Input: `k1:
+- "k2: v2"
+`,
+			Expected: `[
+  {
+    "op": "replace",
+    "path": "/k1/0",
+    "value": "k2: v2-modified1\n"
+  }
+]`,
+		},
+
+		// Test case 3, unchanged values result in an empty patch.
+		{
+			ValueModifiers: []ValueModifier{
+				testModifierNoop{},
+			},
+			SelectFields: []string{},
+			Input: `{
+  "k1": "v1"
+}`,
+			Expected: `[]`,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			config := DefaultConfig()
+			config.ValueModifiers = testCase.ValueModifiers
+			config.SelectFields = testCase.SelectFields
+			config.Output = OutputJSONPatch
+			newService, err := New(config)
+			if err != nil {
+				t.Fatal("expected", nil, "got", err)
+			}
+
+			output, err := newService.Traverse([]byte(testCase.Input))
+			if err != nil {
+				t.Fatal("expected", nil, "got", err)
+			}
+			if string(output) != testCase.Expected {
+				t.Fatal("expected", fmt.Sprintf("%q", testCase.Expected), "got", fmt.Sprintf("%q", output))
+			}
+		})
+	}
+}
+
+// testUnwrapper reverts testModifier1 and fails for values not modified by it.
+type testUnwrapper struct{}
+
+func (m testUnwrapper) Modify(value []byte) ([]byte, error) {
+	if !strings.HasSuffix(string(value), "-modified1") {
+		return nil, microerror.Maskf(executionFailedError, "value %q not modified", value)
+	}
+
+	return []byte(strings.TrimSuffix(string(value), "-modified1")), nil
+}
+
+func Test_ValueModifier_Rotate(t *testing.T) {
+	testCases := []struct {
+		SelectFields    []string
+		Output          string
+		Input           string
+		Expected        string
+		ExpectedRotated []string
+	}{
+		// Test case 0, all values of nested objects and lists are rotated.
+		{
+			Input: `k1:
+  k2: v2-modified1
+k3:
+- v3-modified1
+- k4: v4-modified1
+`,
+			Expected: `k1:
+  k2: v2-modified2
+k3:
+- v3-modified2
+- k4: v4-modified2
+`,
+			ExpectedRotated: []string{
+				"k1.k2",
+				"k3.[0]",
+				"k3.[1].k4",
+			},
+		},
+
+		// Test case 1, only selected fields are rotated.
+		{
+			SelectFields: []string{
+				"k1",
+			},
+			Input: `{
+  "k1": "v1-modified1",
+  "k2": "v2"
+}`,
+			Expected: `{
+  "k1": "v1-modified2",
+  "k2": "v2"
+}`,
+			ExpectedRotated: []string{
+				"k1",
+			},
+		},
+
+		// Test case 2, rotated values are returned as JSON Patch.
+		{
+			SelectFields: []string{
+				"k1",
+			},
+			Output: OutputJSONPatch,
+			Input: `{
+  "k1": "v1-modified1",
+  "k2": "v2"
+}`,
+			Expected: `[
+  {
+    "op": "replace",
+    "path": "/k1",
+    "value": "v1-modified2"
+  }
+]`,
+			ExpectedRotated: []string{
+				"k1",
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			config := DefaultConfig()
+			config.SelectFields = testCase.SelectFields
+			config.Output = testCase.Output
+			newService, err := New(config)
+			if err != nil {
+				t.Fatal("expected", nil, "got", err)
+			}
+
+			oldValueModifiers := []ValueModifier{
+				testUnwrapper{},
+			}
+			newValueModifiers := []ValueModifier{
+				testModifier2{},
+			}
+			output, rotated, err := newService.Rotate([]byte(testCase.Input), oldValueModifiers, newValueModifiers)
+			if err != nil {
+				t.Fatal("expected", nil, "got", err)
+			}
+			if string(output) != testCase.Expected {
+				t.Fatal("expected", fmt.Sprintf("%q", testCase.Expected), "got", fmt.Sprintf("%q", output))
+			}
+			if !reflect.DeepEqual(rotated, testCase.ExpectedRotated) {
+				t.Fatal("expected", testCase.ExpectedRotated, "got", rotated)
+			}
+		})
+	}
+}
+
+func Test_ValueModifier_Rotate_Error(t *testing.T) {
+	newService, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Values which cannot be reverted by the old value modifiers fail the
+	// whole rotation.
+	input := []byte(`{
+  "k1": "v1-modified1",
+  "k2": "v2"
+}`)
+	_, _, err = newService.Rotate(input, []ValueModifier{testUnwrapper{}}, []ValueModifier{testModifier2{}})
+	if !IsExecutionFailed(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+
+	// Old and new value modifiers must be given.
+	_, _, err = newService.Rotate(input, nil, []ValueModifier{testModifier2{}})
+	if !IsInvalidConfig(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+	_, _, err = newService.Rotate(input, []ValueModifier{testUnwrapper{}}, []ValueModifier{})
+	if !IsInvalidConfig(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+	// Traversing requires the configured value modifiers, which are not
+	// needed to rotate values.
+	_, err = newService.Traverse(input)
+	if !IsInvalidConfig(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+// testPathModifier appends the path of the modified value.
+type testPathModifier struct{}
+
+func (m testPathModifier) Modify(value []byte) ([]byte, error) {
+	return nil, microerror.Maskf(executionFailedError, "path required")
+}
+
+func (m testPathModifier) ModifyPath(path string, value []byte) ([]byte, error) {
+	return []byte(string(value) + "-" + path), nil
+}
+
+func Test_ValueModifier_Traverse_PathValueModifier(t *testing.T) {
+	config := DefaultConfig()
+	config.ValueModifiers = []ValueModifier{
+		testPathModifier{},
+		testModifier1{},
+	}
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	input := `k1:
+  k2: v2
+k3:
+- v3
+`
+	expected := `k1:
+  k2: v2-k1.k2-modified1
+k3:
+- v3-k3.[0]-modified1
+`
+	output, err := newService.Traverse([]byte(input))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(output) != expected {
+		t.Fatal("expected", fmt.Sprintf("%q", expected), "got", fmt.Sprintf("%q", output))
+	}
+}
+
+// testTraversalModifier appends the number of the traversal the value was
+// modified in.
+type testTraversalModifier struct {
+	Traversals int
+	Active     bool
+}
+
+func (m *testTraversalModifier) BeginTraversal() error {
+	m.Traversals++
+	m.Active = true
+	return nil
+}
+
+func (m *testTraversalModifier) EndTraversal() {
+	m.Active = false
+}
+
+func (m *testTraversalModifier) Modify(value []byte) ([]byte, error) {
+	if !m.Active {
+		return nil, microerror.Maskf(executionFailedError, "traversal not active")
+	}
+
+	return []byte(string(value) + "-" + strconv.Itoa(m.Traversals)), nil
+}
+
+func Test_ValueModifier_Traverse_TraversalValueModifier(t *testing.T) {
+	m := &testTraversalModifier{}
+
+	config := DefaultConfig()
+	config.ValueModifiers = []ValueModifier{
+		m,
+	}
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	input := `k1: v1
+k2: v2
+`
+	for i, expected := range []string{"k1: v1-1\nk2: v2-1\n", "k1: v1-2\nk2: v2-2\n"} {
+		output, err := newService.Traverse([]byte(input))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(output) != expected {
+			t.Fatal("test", i+1, "expected", fmt.Sprintf("%q", expected), "got", fmt.Sprintf("%q", output))
+		}
+		if m.Active {
+			t.Fatal("test", i+1, "expected", false, "got", m.Active)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+const (
+	// MethodToken authenticates using a token read from a file.
+	MethodToken = "token"
+	// MethodAppRole authenticates using the AppRole auth method.
+	MethodAppRole = "approle"
+	// MethodKubernetes authenticates using the Kubernetes auth method and the
+	// service account token of the current pod.
+	MethodKubernetes = "kubernetes"
+)
+
+// DefaultKubernetesJWTFile is the file the service account token of a pod is
+// mounted at by default.
+const DefaultKubernetesJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Config represents the configuration used to create a new Vault
+// authentication helper.
+type Config struct {
+	// Settings.
+
+	// VaultConfig is the configuration the Vault client is created with, e.g.
+	// to configure TLS. When nil vaultclient.DefaultConfig is used, which
+	// respects the common VAULT_* environment variables.
+	VaultConfig *vaultclient.Config
+	// Address is the address of Vault. When empty the address of VaultConfig
+	// is used.
+	Address string
+	// Namespace is the Vault Enterprise namespace to authenticate in.
+	Namespace string
+	// Method is the authentication method. It is one of MethodToken,
+	// MethodAppRole or MethodKubernetes.
+	Method string
+	// MountPath is the path the auth method is mounted at. It defaults to the
+	// name of the auth method, e.g. "approle". It is not used by MethodToken.
+	MountPath string
+
+	// TokenFile is the file the token is read from when using MethodToken. The
+	// file is read again on every login, so that rotated tokens are picked up.
+	TokenFile string
+
+	// RoleID is the role ID used by MethodAppRole.
+	RoleID string
+	// SecretID is the secret ID used by MethodAppRole. It must not be used
+	// together with SecretIDFile.
+	SecretID string
+	// SecretIDFile is the file the secret ID used by MethodAppRole is read
+	// from. It must not be used together with SecretID.
+	SecretIDFile string
+
+	// Role is the role used by MethodKubernetes.
+	Role string
+	// JWTFile is the file the service account token used by MethodKubernetes is
+	// read from. It defaults to DefaultKubernetesJWTFile.
+	JWTFile string
+}
+
+// DefaultConfig provides a default configuration to create a new Vault
+// authentication helper by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		VaultConfig: nil,
+		Address:     "",
+		Namespace:   "",
+		Method:      "",
+		MountPath:   "",
+
+		TokenFile: "",
+
+		RoleID:       "",
+		SecretID:     "",
+		SecretIDFile: "",
+
+		Role:    "",
+		JWTFile: DefaultKubernetesJWTFile,
+	}
+}
+
+// New creates a new configured Vault authentication helper. New does not talk
+// to Vault. Call Login before passing the client returned by Client to any of
+// the Vault value modifiers.
+func New(config Config) (*Service, error) {
+	// Settings.
+	switch config.Method {
+	case MethodToken:
+		if config.TokenFile == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.TokenFile must not be empty when config.Method is %q", MethodToken)
+		}
+	case MethodAppRole:
+		if config.RoleID == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.RoleID must not be empty when config.Method is %q", MethodAppRole)
+		}
+		if config.SecretID != "" && config.SecretIDFile != "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.SecretID must be empty when config.SecretIDFile provided")
+		}
+	case MethodKubernetes:
+		if config.Role == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.Role must not be empty when config.Method is %q", MethodKubernetes)
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Method must be one of %q, %q or %q", MethodToken, MethodAppRole, MethodKubernetes)
+	}
+
+	vaultConfig := config.VaultConfig
+	if vaultConfig == nil {
+		vaultConfig = vaultclient.DefaultConfig()
+	}
+	if config.Address != "" {
+		vaultConfig.Address = config.Address
+	}
+	vaultClient, err := vaultclient.NewClient(vaultConfig)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultConfig must be valid: %s", err.Error())
+	}
+	if config.Namespace != "" {
+		vaultClient.SetNamespace(config.Namespace)
+	}
+	// The token must only ever be set by Login, not by the environment.
+	vaultClient.ClearToken()
+
+	mountPath := strings.Trim(config.MountPath, "/")
+	if mountPath == "" {
+		mountPath = config.Method
+	}
+
+	jwtFile := config.JWTFile
+	if jwtFile == "" {
+		jwtFile = DefaultKubernetesJWTFile
+	}
+
+	newService := &Service{
+		// Internals.
+		vaultClient: vaultClient,
+
+		// Settings.
+		method:       config.Method,
+		mountPath:    mountPath,
+		tokenFile:    config.TokenFile,
+		roleID:       config.RoleID,
+		secretID:     config.SecretID,
+		secretIDFile: config.SecretIDFile,
+		role:         config.Role,
+		jwtFile:      jwtFile,
+	}
+
+	return newService, nil
+}
+
+// Service implements the Vault authentication helper.
+type Service struct {
+	// Internals.
+	vaultClient *vaultclient.Client
+	mutex       sync.Mutex
+	secret      *vaultclient.Secret
+
+	// Settings.
+	method       string
+	mountPath    string
+	tokenFile    string
+	roleID       string
+	secretID     string
+	secretIDFile string
+	role         string
+	jwtFile      string
+}
+
+// Client returns the Vault client authenticated by Login. The very same client
+// is kept authenticated by Run, so it can be passed to the Vault value
+// modifiers once.
+func (s *Service) Client() *vaultclient.Client {
+	return s.vaultClient
+}
+
+// Login authenticates against Vault using the configured auth method and sets
+// the resulting token on the client returned by Client.
+func (s *Service) Login(ctx context.Context) error {
+	var secret *vaultclient.Secret
+	var err error
+
+	switch s.method {
+	case MethodToken:
+		secret, err = s.loginToken(ctx)
+	case MethodAppRole:
+		secret, err = s.loginAppRole(ctx)
+	case MethodKubernetes:
+		secret, err = s.loginKubernetes(ctx)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The token of the shared client is only replaced once the login
+	// succeeded, so that requests sent concurrently by the Vault value
+	// modifiers are never sent without token.
+	s.mutex.Lock()
+	s.secret = secret
+	s.vaultClient.SetToken(secret.Auth.ClientToken)
+	s.mutex.Unlock()
+
+	return nil
+}
+
+// Run keeps the token of the client returned by Client valid until the given
+// context is cancelled. Renewable tokens are renewed, and a new login is
+// performed once a token cannot be renewed any longer. Login is called first
+// when it was not called before. Run blocks and only returns an error when
+// logging in fails.
+func (s *Service) Run(ctx context.Context) error {
+	s.mutex.Lock()
+	secret := s.secret
+	s.mutex.Unlock()
+
+	if secret == nil {
+		err := s.Login(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for {
+		s.mutex.Lock()
+		secret := s.secret
+		s.mutex.Unlock()
+
+		err := s.watch(ctx, secret)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = s.Login(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+}
+
+// watch renews the given secret until it cannot be renewed any longer or the
+// given context is cancelled.
+func (s *Service) watch(ctx context.Context, secret *vaultclient.Secret) error {
+	if secret.Auth == nil || secret.Auth.LeaseDuration == 0 {
+		// Tokens without TTL never expire, so there is nothing to renew.
+		<-ctx.Done()
+		return nil
+	}
+
+	watcher, err := s.vaultClient.NewLifetimeWatcher(&vaultclient.LifetimeWatcherInput{
+		Secret: secret,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-watcher.RenewCh():
+		case <-watcher.DoneCh():
+			// Errors of renewing are not returned, since a new login is
+			// performed in any case.
+			return nil
+		}
+	}
+}
+
+func (s *Service) loginToken(ctx context.Context) (*vaultclient.Secret, error) {
+	token, err := readFile(s.tokenFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	loginClient, err := s.loginClient()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	loginClient.SetToken(token)
+
+	lookup, err := loginClient.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return nil, microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+
+	renewable, err := lookup.TokenIsRenewable()
+	if err != nil {
+		return nil, microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+	ttl, err := lookup.TokenTTL()
+	if err != nil {
+		return nil, microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+
+	secret := &vaultclient.Secret{
+		Auth: &vaultclient.SecretAuth{
+			ClientToken:   token,
+			Renewable:     renewable,
+			LeaseDuration: int(ttl / time.Second),
+		},
+	}
+
+	return secret, nil
+}
+
+func (s *Service) loginAppRole(ctx context.Context) (*vaultclient.Secret, error) {
+	secretID := s.secretID
+	if s.secretIDFile != "" {
+		var err error
+		secretID, err = readFile(s.secretIDFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	data := map[string]interface{}{
+		"role_id": s.roleID,
+	}
+	if secretID != "" {
+		data["secret_id"] = secretID
+	}
+
+	secret, err := s.login(ctx, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return secret, nil
+}
+
+func (s *Service) loginKubernetes(ctx context.Context) (*vaultclient.Secret, error) {
+	jwt, err := readFile(s.jwtFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	data := map[string]interface{}{
+		"role": s.role,
+		"jwt":  jwt,
+	}
+
+	secret, err := s.login(ctx, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return secret, nil
+}
+
+func (s *Service) login(ctx context.Context, data map[string]interface{}) (*vaultclient.Secret, error) {
+	loginClient, err := s.loginClient()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	secret, err := vaultapi.NewClient(loginClient, "").WriteWithContext(ctx, "auth/"+s.mountPath+"/login", data)
+	if vaultapi.IsVaultUnavailable(err) {
+		return nil, microerror.Mask(err)
+	} else if err != nil {
+		return nil, microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+	if secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, microerror.Maskf(loginFailedError, "Vault response must contain a client token")
+	}
+
+	return secret, nil
+}
+
+// loginClient returns a copy of the shared client without any token, which
+// is used to log in without touching the token of the shared client. Headers
+// are copied in order to keep the namespace.
+func (s *Service) loginClient() (*vaultclient.Client, error) {
+	loginClient, err := s.vaultClient.CloneWithHeaders()
+	if err != nil {
+		return nil, microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+	loginClient.ClearToken()
+
+	return loginClient, nil
+}
+
+func readFile(name string) (string, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return "", microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return "", microerror.Maskf(loginFailedError, "file %q must not be empty", name)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,290 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a stand-in for Vault supporting the AppRole and
+// Kubernetes auth methods as well as looking up and renewing tokens. Renewals
+// are counted using the given counter.
+func newTestServer(t *testing.T, renewals *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]interface{}
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(&data)
+		}
+
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			if r.Header.Get("X-Vault-Token") != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["login requests must not send a token"]}`))
+				return
+			}
+			if data["role_id"] != "role" || data["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid role or secret ID"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"approle-token","renewable":true,"lease_duration":2}}`))
+		case "/v1/auth/k8s/login":
+			if data["role"] != "app" || data["jwt"] != "jwt" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"kubernetes-token","renewable":false,"lease_duration":0}}`))
+		case "/v1/auth/token/lookup-self":
+			if r.Header.Get("X-Vault-Token") != "file-token" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"id":"file-token","renewable":false,"ttl":0}}`))
+		case "/v1/auth/token/renew-self":
+			atomic.AddInt64(renewals, 1)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"` + r.Header.Get("X-Vault-Token") + `","renewable":true,"lease_duration":2}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func writeFile(t *testing.T, content string) string {
+	name := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(name, []byte(content), 0600)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return name
+}
+
+func Test_Auth_Service_Login(t *testing.T) {
+	var renewals int64
+	server := newTestServer(t, &renewals)
+	defer server.Close()
+
+	testCases := []struct {
+		Config        func(t *testing.T) Config
+		ExpectedToken string
+		ErrorMatcher  func(error) bool
+	}{
+		// Test case 1, AppRole logins set the client token.
+		{
+			Config: func(t *testing.T) Config {
+				config := DefaultConfig()
+				config.Method = MethodAppRole
+				config.RoleID = "role"
+				config.SecretIDFile = writeFile(t, "secret\n")
+				return config
+			},
+			ExpectedToken: "approle-token",
+		},
+		// Test case 2, Kubernetes logins use the configured mount path and
+		// service account token.
+		{
+			Config: func(t *testing.T) Config {
+				config := DefaultConfig()
+				config.Method = MethodKubernetes
+				config.MountPath = "k8s"
+				config.Role = "app"
+				config.JWTFile = writeFile(t, "jwt")
+				return config
+			},
+			ExpectedToken: "kubernetes-token",
+		},
+		// Test case 3, tokens are read from files.
+		{
+			Config: func(t *testing.T) Config {
+				config := DefaultConfig()
+				config.Method = MethodToken
+				config.TokenFile = writeFile(t, "file-token\n")
+				return config
+			},
+			ExpectedToken: "file-token",
+		},
+		// Test case 4, invalid AppRole credentials fail the login.
+		{
+			Config: func(t *testing.T) Config {
+				config := DefaultConfig()
+				config.Method = MethodAppRole
+				config.RoleID = "role"
+				config.SecretID = "wrong"
+				return config
+			},
+			ErrorMatcher: IsLoginFailed,
+		},
+		// Test case 5, invalid tokens fail the login.
+		{
+			Config: func(t *testing.T) Config {
+				config := DefaultConfig()
+				config.Method = MethodToken
+				config.TokenFile = writeFile(t, "wrong")
+				return config
+			},
+			ErrorMatcher: IsLoginFailed,
+		},
+		// Test case 6, missing files fail the login.
+		{
+			Config: func(t *testing.T) Config {
+				config := DefaultConfig()
+				config.Method = MethodKubernetes
+				config.Role = "app"
+				config.JWTFile = filepath.Join(t.TempDir(), "missing")
+				return config
+			},
+			ErrorMatcher: IsLoginFailed,
+		},
+	}
+
+	for i, tc := range testCases {
+		config := tc.Config(t)
+		config.Address = server.URL
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		err = newService.Login(context.Background())
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if newService.Client().Token() != tc.ExpectedToken {
+			t.Fatal("test", i+1, "expected", tc.ExpectedToken, "got", newService.Client().Token())
+		}
+	}
+}
+
+func Test_Auth_Service_Login_Relogin(t *testing.T) {
+	var renewals int64
+	server := newTestServer(t, &renewals)
+	defer server.Close()
+
+	secretIDFile := writeFile(t, "secret")
+
+	config := DefaultConfig()
+	config.Address = server.URL
+	config.Method = MethodAppRole
+	config.RoleID = "role"
+	config.SecretIDFile = secretIDFile
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	err = newService.Login(context.Background())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Logging in again does not send the token of the shared client.
+	err = newService.Login(context.Background())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Failing logins keep the token of the shared client.
+	err = os.WriteFile(secretIDFile, []byte("wrong"), 0600)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = newService.Login(context.Background())
+	if !IsLoginFailed(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+	if newService.Client().Token() != "approle-token" {
+		t.Fatal("expected", "approle-token", "got", newService.Client().Token())
+	}
+}
+
+func Test_Auth_Service_Run(t *testing.T) {
+	var renewals int64
+	server := newTestServer(t, &renewals)
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.Address = server.URL
+	config.Method = MethodAppRole
+	config.RoleID = "role"
+	config.SecretID = "secret"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- newService.Run(ctx)
+	}()
+
+	// Renewable tokens are renewed.
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadInt64(&renewals) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected", "renewal", "got", "none")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if newService.Client().Token() != "approle-token" {
+		t.Fatal("expected", "approle-token", "got", newService.Client().Token())
+	}
+
+	cancel()
+	err = <-done
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+}
+
+func Test_Auth_New_Error(t *testing.T) {
+	testCases := []struct {
+		Config Config
+	}{
+		// Test case 1, the method is required.
+		{
+			Config: Config{},
+		},
+		// Test case 2, the token file is required for the token method.
+		{
+			Config: Config{
+				Method: MethodToken,
+			},
+		},
+		// Test case 3, the role ID is required for the AppRole method.
+		{
+			Config: Config{
+				Method:   MethodAppRole,
+				SecretID: "secret",
+			},
+		},
+		// Test case 4, the role is required for the Kubernetes method.
+		{
+			Config: Config{
+				Method: MethodKubernetes,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := New(tc.Config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
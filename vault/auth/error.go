@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var loginFailedError = &microerror.Error{
+	Kind: "loginFailedError",
+}
+
+// IsLoginFailed asserts loginFailedError.
+func IsLoginFailed(err error) bool {
+	return microerror.Cause(err) == loginFailedError
+}
+
+// IsVaultUnavailable asserts that Vault could not be reached or failed to
+// process the request.
+func IsVaultUnavailable(err error) bool {
+	return vaultapi.IsVaultUnavailable(err)
+}
@@ -2,10 +2,12 @@ package decrypt
 
 import (
 	"encoding/base64"
-	"fmt"
 
 	"github.com/giantswarm/microerror"
 	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
 )
 
 // Config represents the configuration used to create a new vault decrypting
@@ -13,12 +15,29 @@ import (
 type Config struct {
 	VaultClient *vaultclient.Client
 	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// Context is the context used to derive the decryption key of transit keys
+	// created with key derivation enabled. It must match the context values
+	// were encrypted with. Context must not be used together with PathContext.
+	Context []byte
+	// PathContext uses the path of each traversed value as context. It must be
+	// provided when values were encrypted using config.PathContext of the vault
+	// encrypting value modifier. PathContext requires the value modifier to be
+	// used via Traverse or Rotate.
+	PathContext bool
 }
 
 // DefaultConfig provides a default configuration to create a new vault
 // decrypting value modifier by best effort.
 func DefaultConfig() Config {
-	return Config{}
+	return Config{
+		MountPath: "transit",
+	}
 }
 
 // New creates a new configured vault decrypting value modifier.
@@ -30,9 +49,15 @@ func New(config Config) (*Service, error) {
 		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
 
 	}
+	if len(config.Context) != 0 && config.PathContext {
+		return nil, microerror.Maskf(invalidConfigError, "config.Context must be empty when config.PathContext provided")
+	}
+
 	newService := &Service{
-		vaultClient: config.VaultClient,
-		path:        fmt.Sprintf("/transit/decrypt/%s", config.Key),
+		vaultClient: vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:        transit.Path(config.MountPath, "decrypt", config.Key),
+		context:     config.Context,
+		pathContext: config.PathContext,
 	}
 
 	return newService, nil
@@ -40,8 +65,10 @@ func New(config Config) (*Service, error) {
 
 // Service implements the vault decrypting value modifier.
 type Service struct {
-	vaultClient *vaultclient.Client
+	vaultClient *vaultapi.Client
 	path        string
+	context     []byte
+	pathContext bool
 }
 
 func (s *Service) Modify(value []byte) ([]byte, error) {
@@ -58,14 +85,63 @@ func (s *Service) Modify(value []byte) ([]byte, error) {
 	return decrypted, nil
 }
 
+// ModifyPath works like Modify, but uses the given path as context when
+// config.PathContext is provided.
+func (s *Service) ModifyPath(path string, value []byte) ([]byte, error) {
+	context := s.context
+	if s.pathContext {
+		context = []byte(path)
+	}
+
+	plainText, err := s.decrypt(value, context)
+	if err != nil {
+		return []byte{}, microerror.Mask(err)
+	}
+
+	decrypted, err := base64.StdEncoding.DecodeString(plainText)
+	if err != nil {
+		return []byte{}, microerror.Mask(err)
+	}
+
+	return decrypted, nil
+}
+
 func (s *Service) Decrypt(cipherText []byte) (string, error) {
-	secret, err := s.vaultClient.Logical().Write(s.path, map[string]interface{}{
+	if s.pathContext {
+		return "", microerror.Maskf(missingPathError, "value must be modified via ModifyPath when config.PathContext provided")
+	}
+
+	plainText, err := s.decrypt(cipherText, s.context)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return plainText, nil
+}
+
+func (s *Service) decrypt(cipherText []byte, context []byte) (string, error) {
+	_, err := transit.KeyVersion(string(cipherText))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	data := map[string]interface{}{
 		"ciphertext": string(cipherText),
-	})
+	}
+	if len(context) != 0 {
+		data["context"] = base64.StdEncoding.EncodeToString(context)
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
 
+	plainText, err := vaultapi.String(secret, "plaintext")
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
 
-	return fmt.Sprintf("%v", secret.Data["plaintext"]), nil
+	return plainText, nil
 }
@@ -0,0 +1,271 @@
+package decrypt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/valuemodifier"
+	"github.com/giantswarm/valuemodifier/vault/encrypt"
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func Test_Vault_Decrypt_Service_Modify(t *testing.T) {
+	testCases := []struct {
+		Key          string
+		Value        func(e *encrypt.Service) string
+		Expected     string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, values encrypted by the vault encrypting value modifier
+		// are decrypted.
+		{
+			Key: "foo",
+			Value: func(e *encrypt.Service) string {
+				cipherText, _ := e.Modify([]byte("bar"))
+				return string(cipherText)
+			},
+			Expected: "bar",
+		},
+		// Test case 2, empty values are decrypted.
+		{
+			Key: "foo",
+			Value: func(e *encrypt.Service) string {
+				cipherText, _ := e.Modify([]byte(""))
+				return string(cipherText)
+			},
+			Expected: "",
+		},
+		// Test case 3, values which are no transit cipher texts are rejected
+		// without calling Vault.
+		{
+			Key: "foo",
+			Value: func(e *encrypt.Service) string {
+				return "bar"
+			},
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 4, tampered cipher texts are rejected.
+		{
+			Key: "foo",
+			Value: func(e *encrypt.Service) string {
+				cipherText, _ := e.Modify([]byte("bar"))
+				return string(cipherText[:len(cipherText)-4]) + "AAA="
+			},
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 5, unknown keys are reported.
+		{
+			Key: "unknown",
+			Value: func(e *encrypt.Service) string {
+				cipherText, _ := e.Modify([]byte("bar"))
+				return string(cipherText)
+			},
+			ErrorMatcher: IsKeyNotFound,
+		},
+	}
+
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	var encryptService *encrypt.Service
+	{
+		config := encrypt.DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		encryptService, err = encrypt.New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = tc.Key
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := newService.Modify([]byte(tc.Value(encryptService)))
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != tc.Expected {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", string(modified))
+		}
+	}
+}
+
+func Test_Vault_Decrypt_Traverse_PathContext(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	var encryptTraverser *valuemodifier.Service
+	{
+		config := encrypt.DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.PathContext = true
+		encryptService, err := encrypt.New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{encryptService}
+		encryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	var decryptTraverser *valuemodifier.Service
+	{
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.PathContext = true
+		decryptService, err := New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{decryptService}
+		decryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	input := `k1:
+  k2: v2
+k3:
+- v3
+- v4
+`
+	encrypted, err := encryptTraverser.Traverse([]byte(input))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if strings.Contains(string(encrypted), "v2") || strings.Contains(string(encrypted), "v3") {
+		t.Fatal("expected", "encrypted values", "got", string(encrypted))
+	}
+
+	decrypted, err := decryptTraverser.Traverse(encrypted)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(decrypted) != input {
+		t.Fatal("expected", fmt.Sprintf("%q", input), "got", fmt.Sprintf("%q", decrypted))
+	}
+
+	// Values moved to another path cannot be decrypted anymore, because their
+	// path does not match the context they were encrypted with.
+	lines := strings.Split(string(encrypted), "\n")
+	moved := fmt.Sprintf("k1:\n  k2: %s\n", strings.TrimPrefix(lines[3], "- "))
+	_, err = decryptTraverser.Traverse([]byte(moved))
+	if !IsInvalidCiphertext(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func Test_Vault_Decrypt_Service_Modify_MountPath_Namespace(t *testing.T) {
+	testCases := []struct {
+		Namespace string
+	}{
+		// Test case 1, requests are sent without namespace by default.
+		{
+			Namespace: "",
+		},
+		// Test case 2, requests are sent to the configured namespace.
+		{
+			Namespace: "team-a",
+		},
+	}
+
+	vaultConfig := vaulttest.DefaultConfig()
+	vaultConfig.MountPath = "transit-prod"
+	server, err := vaulttest.New(vaultConfig)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	for i, tc := range testCases {
+		vaultClient, err := server.Client()
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		var encryptService *encrypt.Service
+		{
+			config := encrypt.DefaultConfig()
+			config.VaultClient = vaultClient
+			config.Key = "foo"
+			config.MountPath = "transit-prod"
+			config.Namespace = tc.Namespace
+			encryptService, err = encrypt.New(config)
+			if err != nil {
+				t.Fatal("test", i+1, "expected", nil, "got", err)
+			}
+		}
+
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.MountPath = "transit-prod"
+		config.Namespace = tc.Namespace
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		encrypted, err := encryptService.Modify([]byte("bar"))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		decrypted, err := newService.Modify(encrypted)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(decrypted) != "bar" {
+			t.Fatal("test", i+1, "expected", "bar", "got", string(decrypted))
+		}
+
+		request, ok := server.LastRequest("decrypt")
+		if !ok {
+			t.Fatal("test", i+1, "expected", true, "got", ok)
+		}
+		if request.Path != "decrypt/foo" {
+			t.Fatal("test", i+1, "expected", "decrypt/foo", "got", request.Path)
+		}
+		if request.Namespace != tc.Namespace {
+			t.Fatal("test", i+1, "expected", tc.Namespace, "got", request.Namespace)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package decrypt
 
-import "github.com/giantswarm/microerror"
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
 
 var invalidConfigError = &microerror.Error{
 	Kind: "invalidConfigError",
@@ -15,7 +20,39 @@ var vaultResponseError = &microerror.Error{
 	Kind: "vaultResponseError",
 }
 
-// IsVaultResponseError asserts vaultResponseError.
+// IsVaultResponseError asserts vaultResponseError, which is also returned
+// when Vault responds with unexpected data.
 func IsVaultResponseError(err error) bool {
-	return microerror.Cause(err) == vaultResponseError
+	return microerror.Cause(err) == vaultResponseError || vaultapi.IsInvalidResponse(err)
+}
+
+var missingPathError = &microerror.Error{
+	Kind: "missingPathError",
+}
+
+// IsMissingPath asserts missingPathError.
+func IsMissingPath(err error) bool {
+	return microerror.Cause(err) == missingPathError
+}
+
+// IsInvalidCiphertext asserts that a cipher text is malformed or was rejected by
+// Vault.
+func IsInvalidCiphertext(err error) bool {
+	return transit.IsInvalidCiphertext(err)
+}
+
+// IsKeyNotFound asserts that the transit key or mount does not exist.
+func IsKeyNotFound(err error) bool {
+	return vaultapi.IsKeyNotFound(err)
+}
+
+// IsPermissionDenied asserts that the Vault token lacks permissions.
+func IsPermissionDenied(err error) bool {
+	return vaultapi.IsPermissionDenied(err)
+}
+
+// IsVaultUnavailable asserts that Vault could not be reached or failed to
+// process the request.
+func IsVaultUnavailable(err error) bool {
+	return vaultapi.IsVaultUnavailable(err)
 }
@@ -2,10 +2,12 @@ package encrypt
 
 import (
 	"encoding/base64"
-	"fmt"
 
 	"github.com/giantswarm/microerror"
 	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
 )
 
 // Config represents the configuration used to create a new vault encrypting
@@ -13,12 +15,37 @@ import (
 type Config struct {
 	VaultClient *vaultclient.Client
 	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// KeyVersion pins the version of the transit key used to encrypt values.
+	// When 0 the latest version is used.
+	KeyVersion int
+	// Context is the context used to derive the encryption key of transit keys
+	// created with key derivation enabled. Context must not be used together
+	// with PathContext.
+	Context []byte
+	// PathContext uses the path of each traversed value as context, which
+	// binds encrypted values to their location within a document. Values can
+	// then only be decrypted at the very same path. PathContext requires the
+	// value modifier to be used via Traverse or Rotate.
+	PathContext bool
+	// Convergent requests convergent encryption, so that the same plain text
+	// always results in the same cipher text given the same context. The
+	// transit key must have been created with convergent encryption enabled,
+	// which also requires either Context or PathContext.
+	Convergent bool
 }
 
 // DefaultConfig provides a default configuration to create a new vault
 // encrypting value modifier by best effort.
 func DefaultConfig() Config {
-	return Config{}
+	return Config{
+		MountPath: "transit",
+	}
 }
 
 // New creates a new configured vault encrypting value modifier.
@@ -30,9 +57,23 @@ func New(config Config) (*Service, error) {
 		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
 
 	}
+	if config.KeyVersion < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.KeyVersion must not be negative")
+	}
+	if len(config.Context) != 0 && config.PathContext {
+		return nil, microerror.Maskf(invalidConfigError, "config.Context must be empty when config.PathContext provided")
+	}
+	if config.Convergent && len(config.Context) == 0 && !config.PathContext {
+		return nil, microerror.Maskf(invalidConfigError, "config.Context or config.PathContext must be defined when config.Convergent provided")
+	}
+
 	newService := &Service{
-		vaultClient: config.VaultClient,
-		path:        fmt.Sprintf("/transit/encrypt/%s", config.Key),
+		vaultClient: vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:        transit.Path(config.MountPath, "encrypt", config.Key),
+		keyVersion:  config.KeyVersion,
+		context:     config.Context,
+		pathContext: config.PathContext,
+		convergent:  config.Convergent,
 	}
 
 	return newService, nil
@@ -40,14 +81,36 @@ func New(config Config) (*Service, error) {
 
 // Service implements the vault encrypting value modifier.
 type Service struct {
-	vaultClient *vaultclient.Client
+	vaultClient *vaultapi.Client
 	path        string
+	keyVersion  int
+	context     []byte
+	pathContext bool
+	convergent  bool
 }
 
 func (s *Service) Modify(value []byte) ([]byte, error) {
-	base64Encoded := base64.StdEncoding.EncodeToString(value)
+	if s.pathContext {
+		return nil, microerror.Maskf(missingPathError, "value must be modified via ModifyPath when config.PathContext provided")
+	}
 
-	cipherText, err := s.Encrypt(base64Encoded)
+	cipherText, err := s.encrypt(base64.StdEncoding.EncodeToString(value), s.context)
+	if err != nil {
+		return []byte{}, microerror.Mask(err)
+	}
+
+	return []byte(cipherText), nil
+}
+
+// ModifyPath works like Modify, but uses the given path as context when
+// config.PathContext is provided.
+func (s *Service) ModifyPath(path string, value []byte) ([]byte, error) {
+	context := s.context
+	if s.pathContext {
+		context = []byte(path)
+	}
+
+	cipherText, err := s.encrypt(base64.StdEncoding.EncodeToString(value), context)
 	if err != nil {
 		return []byte{}, microerror.Mask(err)
 	}
@@ -56,13 +119,42 @@ func (s *Service) Modify(value []byte) ([]byte, error) {
 }
 
 func (s *Service) Encrypt(plainText string) (string, error) {
-	secret, err := s.vaultClient.Logical().Write(s.path, map[string]interface{}{
+	if s.pathContext {
+		return "", microerror.Maskf(missingPathError, "value must be modified via ModifyPath when config.PathContext provided")
+	}
+
+	cipherText, err := s.encrypt(plainText, s.context)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return cipherText, nil
+}
+
+func (s *Service) encrypt(plainText string, context []byte) (string, error) {
+	data := map[string]interface{}{
 		"plaintext": plainText,
-	})
+	}
+	if s.keyVersion != 0 {
+		data["key_version"] = s.keyVersion
+	}
+	if len(context) != 0 {
+		data["context"] = base64.StdEncoding.EncodeToString(context)
+	}
+	if s.convergent {
+		data["convergent_encryption"] = true
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
 
+	cipherText, err := vaultapi.String(secret, "ciphertext")
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
 
-	return fmt.Sprintf("%v", secret.Data["ciphertext"]), nil
+	return cipherText, nil
 }
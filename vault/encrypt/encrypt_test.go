@@ -0,0 +1,300 @@
+package encrypt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func Test_Vault_Encrypt_Service_Modify(t *testing.T) {
+	testCases := []struct {
+		Config       func(config Config) Config
+		Modify       func(s *Service) ([]byte, error)
+		Expected     string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, values are encrypted using the latest key version.
+		{
+			Modify: func(s *Service) ([]byte, error) {
+				return s.Modify([]byte("foo"))
+			},
+			Expected: "vault:v2:",
+		},
+		// Test case 2, values are encrypted using the pinned key version.
+		{
+			Config: func(config Config) Config {
+				config.KeyVersion = 1
+				return config
+			},
+			Modify: func(s *Service) ([]byte, error) {
+				return s.Modify([]byte("foo"))
+			},
+			Expected: "vault:v1:",
+		},
+		// Test case 3, values cannot be modified without path when the path
+		// is used as context.
+		{
+			Config: func(config Config) Config {
+				config.PathContext = true
+				return config
+			},
+			Modify: func(s *Service) ([]byte, error) {
+				return s.Modify([]byte("foo"))
+			},
+			ErrorMatcher: IsMissingPath,
+		},
+		// Test case 4, values are encrypted using their path as context.
+		{
+			Config: func(config Config) Config {
+				config.PathContext = true
+				return config
+			},
+			Modify: func(s *Service) ([]byte, error) {
+				return s.ModifyPath("k1.k2", []byte("foo"))
+			},
+			Expected: "vault:v2:",
+		},
+		// Test case 5, requests using another token are denied.
+		{
+			Config: func(config Config) Config {
+				config.VaultClient.SetToken("wrong")
+				return config
+			},
+			Modify: func(s *Service) ([]byte, error) {
+				return s.Modify([]byte("foo"))
+			},
+			ErrorMatcher: IsPermissionDenied,
+		},
+	}
+
+	vaultConfig := vaulttest.DefaultConfig()
+	vaultConfig.Token = "token"
+	server, err := vaulttest.New(vaultConfig)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	_, err = server.RotateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	for i, tc := range testCases {
+		vaultClient, err := server.Client()
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		if tc.Config != nil {
+			config = tc.Config(config)
+		}
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := tc.Modify(newService)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !strings.HasPrefix(string(modified), tc.Expected) {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", string(modified))
+		}
+	}
+}
+
+func Test_Vault_Encrypt_Service_Modify_Convergent(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	config.PathContext = true
+	config.Convergent = true
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	first, err := newService.ModifyPath("k1", []byte("foo"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	second, err := newService.ModifyPath("k1", []byte("foo"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(first) != string(second) {
+		t.Fatal("expected", string(first), "got", string(second))
+	}
+
+	other, err := newService.ModifyPath("k2", []byte("foo"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(first) == string(other) {
+		t.Fatal("expected", "different cipher texts", "got", string(other))
+	}
+}
+
+func Test_Vault_Encrypt_Service_Modify_MountPath_Namespace(t *testing.T) {
+	testCases := []struct {
+		Namespace string
+	}{
+		// Test case 1, requests are sent without namespace by default.
+		{
+			Namespace: "",
+		},
+		// Test case 2, requests are sent to the configured namespace.
+		{
+			Namespace: "team-a",
+		},
+	}
+
+	vaultConfig := vaulttest.DefaultConfig()
+	vaultConfig.MountPath = "transit-prod"
+	server, err := vaulttest.New(vaultConfig)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	for i, tc := range testCases {
+		vaultClient, err := server.Client()
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.MountPath = "transit-prod"
+		config.Namespace = tc.Namespace
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := newService.Modify([]byte("bar"))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if !strings.HasPrefix(string(modified), "vault:v1:") {
+			t.Fatal("test", i+1, "expected", "vault:v1:", "got", string(modified))
+		}
+
+		request, ok := server.LastRequest("encrypt")
+		if !ok {
+			t.Fatal("test", i+1, "expected", true, "got", ok)
+		}
+		if request.Path != "encrypt/foo" {
+			t.Fatal("test", i+1, "expected", "encrypt/foo", "got", request.Path)
+		}
+		if request.Namespace != tc.Namespace {
+			t.Fatal("test", i+1, "expected", tc.Namespace, "got", request.Namespace)
+		}
+	}
+
+	// The default mount path is not served by the stand-in.
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	_, err = newService.Modify([]byte("bar"))
+	if !IsKeyNotFound(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func Test_Vault_Encrypt_Service_Modify_Token(t *testing.T) {
+	testCases := []struct {
+		Namespace string
+	}{
+		// Test case 1, tokens set after New are used without namespace.
+		{
+			Namespace: "",
+		},
+		// Test case 2, tokens set after New are used with namespace.
+		{
+			Namespace: "team-a",
+		},
+	}
+
+	vaultConfig := vaulttest.DefaultConfig()
+	vaultConfig.Token = "token-b"
+	server, err := vaulttest.New(vaultConfig)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	for i, tc := range testCases {
+		vaultClient, err := server.Client()
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		vaultClient.SetToken("token-a")
+
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.Namespace = tc.Namespace
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		_, err = newService.Modify([]byte("bar"))
+		if !IsPermissionDenied(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+
+		// A token set on the given client after New, e.g. by vault/auth, is
+		// used for all further requests.
+		vaultClient.SetToken("token-b")
+
+		_, err = newService.Modify([]byte("bar"))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		request, ok := server.LastRequest("encrypt")
+		if !ok {
+			t.Fatal("test", i+1, "expected", true, "got", ok)
+		}
+		if request.Namespace != tc.Namespace {
+			t.Fatal("test", i+1, "expected", tc.Namespace, "got", request.Namespace)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package decrypt
+
+import (
+	"encoding/base64"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/envelope"
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+// Config represents the configuration used to create a new vault envelope
+// decrypting value modifier.
+type Config struct {
+	VaultClient *vaultclient.Client
+	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// Context is the context used to derive the decryption key of transit keys
+	// created with key derivation enabled. It must match the context values
+	// were encrypted with.
+	Context []byte
+}
+
+// DefaultConfig provides a default configuration to create a new vault
+// envelope decrypting value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		MountPath: "transit",
+	}
+}
+
+// New creates a new configured vault envelope decrypting value modifier.
+func New(config Config) (*Service, error) {
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must be defined")
+	}
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
+	}
+
+	newService := &Service{
+		vaultClient: vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:        transit.Path(config.MountPath, "decrypt", config.Key),
+		context:     config.Context,
+	}
+
+	return newService, nil
+}
+
+// Service implements the vault envelope decrypting value modifier. It
+// implements valuemodifier.TraversalValueModifier, so that every data key is
+// only unwrapped once per traversed document.
+type Service struct {
+	vaultClient *vaultapi.Client
+	path        string
+	context     []byte
+
+	// dataKeys holds the unwrapped data keys of the current traversal, keyed
+	// by their wrapped form. It is nil outside of traversals.
+	dataKeys map[string][]byte
+}
+
+// BeginTraversal makes all values modified until EndTraversal share the data
+// keys unwrapped by Vault.
+func (s *Service) BeginTraversal() error {
+	s.dataKeys = map[string][]byte{}
+
+	return nil
+}
+
+// EndTraversal forgets the data keys of the current traversal.
+func (s *Service) EndTraversal() {
+	for _, k := range s.dataKeys {
+		clear(k)
+	}
+	s.dataKeys = nil
+}
+
+// Modify decrypts values produced by the vault envelope encrypting value
+// modifier. Outside of traversals the data key of every value is unwrapped
+// separately.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	wrappedKey, sealed, err := envelope.Split(value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	key, ok := s.dataKeys[wrappedKey]
+	if !ok {
+		key, err = s.unwrap(wrappedKey)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if s.dataKeys != nil {
+			s.dataKeys[wrappedKey] = key
+		} else {
+			defer clear(key)
+		}
+	}
+
+	plainText, err := envelope.Open(key, wrappedKey, sealed)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return plainText, nil
+}
+
+func (s *Service) unwrap(wrappedKey string) ([]byte, error) {
+	_, err := transit.KeyVersion(wrappedKey)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	data := map[string]interface{}{
+		"ciphertext": wrappedKey,
+	}
+	if len(s.context) != 0 {
+		data["context"] = base64.StdEncoding.EncodeToString(s.context)
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	plainText, err := vaultapi.String(secret, "plaintext")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(plainText)
+	if err != nil {
+		return nil, microerror.Maskf(vaultResponseError, "Vault response field %q must be base64 encoded", "plaintext")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,102 @@
+package decrypt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/valuemodifier"
+	"github.com/giantswarm/valuemodifier/vault/envelope/encrypt"
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func Test_Vault_Envelope_Decrypt_Traverse(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	var encryptTraverser *valuemodifier.Service
+	{
+		config := encrypt.DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		encryptService, err := encrypt.New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{encryptService}
+		encryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	var decryptTraverser *valuemodifier.Service
+	{
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		decryptService, err := New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{decryptService}
+		decryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	input := `k1:
+  k2: v2
+k3:
+- v3
+- v4
+`
+	encrypted, err := encryptTraverser.Traverse([]byte(input))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if strings.Count(string(encrypted), "vaultenv:v1:") != 3 {
+		t.Fatal("expected", 3, "got", string(encrypted))
+	}
+	if server.Requests("datakey") != 1 {
+		t.Fatal("expected", 1, "got", server.Requests("datakey"))
+	}
+
+	decrypted, err := decryptTraverser.Traverse(encrypted)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(decrypted) != input {
+		t.Fatal("expected", fmt.Sprintf("%q", input), "got", fmt.Sprintf("%q", decrypted))
+	}
+	if server.Requests("decrypt") != 1 {
+		t.Fatal("expected", 1, "got", server.Requests("decrypt"))
+	}
+
+	// Every traversal uses a new data key.
+	_, err = encryptTraverser.Traverse([]byte(input))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if server.Requests("datakey") != 2 {
+		t.Fatal("expected", 2, "got", server.Requests("datakey"))
+	}
+}
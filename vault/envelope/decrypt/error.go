@@ -0,0 +1,50 @@
+package decrypt
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/envelope"
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var vaultResponseError = &microerror.Error{
+	Kind: "vaultResponseError",
+}
+
+// IsVaultResponseError asserts vaultResponseError, which is also returned
+// when Vault responds with unexpected data.
+func IsVaultResponseError(err error) bool {
+	return microerror.Cause(err) == vaultResponseError || vaultapi.IsInvalidResponse(err)
+}
+
+// IsInvalidCiphertext asserts that an envelope or the data key wrapped inside
+// is malformed or was rejected.
+func IsInvalidCiphertext(err error) bool {
+	return envelope.IsInvalidEnvelope(err) || transit.IsInvalidCiphertext(err)
+}
+
+// IsKeyNotFound asserts that the transit key or mount does not exist.
+func IsKeyNotFound(err error) bool {
+	return vaultapi.IsKeyNotFound(err)
+}
+
+// IsPermissionDenied asserts that the Vault token lacks permissions.
+func IsPermissionDenied(err error) bool {
+	return vaultapi.IsPermissionDenied(err)
+}
+
+// IsVaultUnavailable asserts that Vault could not be reached or failed to
+// process the request.
+func IsVaultUnavailable(err error) bool {
+	return vaultapi.IsVaultUnavailable(err)
+}
@@ -0,0 +1,172 @@
+package encrypt
+
+import (
+	"encoding/base64"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/envelope"
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+// Config represents the configuration used to create a new vault envelope
+// encrypting value modifier.
+type Config struct {
+	VaultClient *vaultclient.Client
+	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// Bits is the size of the data keys in bits, either 128 or 256. It
+	// defaults to 256.
+	Bits int
+	// Context is the context used to derive the encryption key of transit keys
+	// created with key derivation enabled.
+	Context []byte
+}
+
+// DefaultConfig provides a default configuration to create a new vault
+// envelope encrypting value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		MountPath: "transit",
+		Bits:      256,
+	}
+}
+
+// New creates a new configured vault envelope encrypting value modifier.
+func New(config Config) (*Service, error) {
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must be defined")
+	}
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
+	}
+
+	bits := config.Bits
+	switch bits {
+	case 0:
+		bits = 256
+	case 128, 256:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Bits must be 128 or 256")
+	}
+
+	newService := &Service{
+		vaultClient: vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:        transit.Path(config.MountPath, "datakey/plaintext", config.Key),
+		bits:        bits,
+		context:     config.Context,
+	}
+
+	return newService, nil
+}
+
+// Service implements the vault envelope encrypting value modifier. It
+// implements valuemodifier.TraversalValueModifier, so that all values of a
+// traversed document share a single data key.
+type Service struct {
+	vaultClient *vaultapi.Client
+	path        string
+	bits        int
+	context     []byte
+
+	dataKey *dataKey
+	// traversing is true between BeginTraversal and EndTraversal.
+	traversing bool
+}
+
+type dataKey struct {
+	Key        []byte
+	WrappedKey string
+}
+
+// BeginTraversal makes all values modified until EndTraversal share a single
+// data key, which is requested from Vault once the first value is modified.
+func (s *Service) BeginTraversal() error {
+	s.dataKey = nil
+	s.traversing = true
+
+	return nil
+}
+
+// EndTraversal forgets the data key of the current traversal.
+func (s *Service) EndTraversal() {
+	if s.dataKey != nil {
+		clear(s.dataKey.Key)
+	}
+	s.dataKey = nil
+	s.traversing = false
+}
+
+// Modify encrypts the given value locally using AES-GCM and returns it
+// together with the data key wrapped by Vault, in the form
+// "vaultenv:v1:<base64 wrapped key>:<base64 nonce and cipher text>". Outside
+// of traversals every value is encrypted using a new data key.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	k := s.dataKey
+	if k == nil {
+		var err error
+		k, err = s.newDataKey()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if s.traversing {
+			s.dataKey = k
+		} else {
+			defer clear(k.Key)
+		}
+	}
+
+	sealed, err := envelope.Seal(k.Key, k.WrappedKey, value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return sealed, nil
+}
+
+func (s *Service) newDataKey() (*dataKey, error) {
+	data := map[string]interface{}{
+		"bits": s.bits,
+	}
+	if len(s.context) != 0 {
+		data["context"] = base64.StdEncoding.EncodeToString(s.context)
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	plainText, err := vaultapi.String(secret, "plaintext")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	wrappedKey, err := vaultapi.String(secret, "ciphertext")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	_, err = transit.KeyVersion(wrappedKey)
+	if err != nil {
+		return nil, microerror.Maskf(vaultResponseError, "Vault response field %q must be of the form vault:v<version>:<cipher text>", "ciphertext")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(plainText)
+	if err != nil || len(key)*8 != s.bits {
+		return nil, microerror.Maskf(vaultResponseError, "Vault response field %q must be a base64 encoded %d bit key", "plaintext", s.bits)
+	}
+
+	k := &dataKey{
+		Key:        key,
+		WrappedKey: wrappedKey,
+	}
+
+	return k, nil
+}
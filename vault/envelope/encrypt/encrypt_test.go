@@ -0,0 +1,240 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func newTestServer(t *testing.T) (*vaulttest.Server, *vaultclient.Client) {
+	t.Helper()
+
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	t.Cleanup(server.Close)
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	return server, vaultClient
+}
+
+// wrappedKey returns the wrapped data key of the given envelope.
+func wrappedKey(t *testing.T, envelope []byte) string {
+	t.Helper()
+
+	parts := strings.Split(string(envelope), ":")
+	if len(parts) != 4 || parts[0] != "vaultenv" || parts[1] != "v1" {
+		t.Fatal("expected", "vaultenv:v1:<wrapped key>:<cipher text>", "got", string(envelope))
+	}
+
+	return parts[2]
+}
+
+func Test_Vault_Envelope_Encrypt_Service_Modify_Traversal(t *testing.T) {
+	server, vaultClient := newTestServer(t)
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	err = newService.BeginTraversal()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	var wrappedKeys []string
+	for _, value := range []string{"v1", "v2", "v3"} {
+		encrypted, err := newService.Modify([]byte(value))
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+		wrappedKeys = append(wrappedKeys, wrappedKey(t, encrypted))
+	}
+
+	newService.EndTraversal()
+
+	// All values of a traversal share a single data key, which is requested
+	// once.
+	if server.Requests("datakey") != 1 {
+		t.Fatal("expected", 1, "got", server.Requests("datakey"))
+	}
+	for _, k := range wrappedKeys[1:] {
+		if k != wrappedKeys[0] {
+			t.Fatal("expected", wrappedKeys[0], "got", k)
+		}
+	}
+
+	// The next traversal requests a new data key.
+	err = newService.BeginTraversal()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	encrypted, err := newService.Modify([]byte("v1"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	newService.EndTraversal()
+
+	if server.Requests("datakey") != 2 {
+		t.Fatal("expected", 2, "got", server.Requests("datakey"))
+	}
+	if wrappedKey(t, encrypted) == wrappedKeys[0] {
+		t.Fatal("expected", "new data key", "got", wrappedKeys[0])
+	}
+}
+
+func Test_Vault_Envelope_Encrypt_Service_Modify_NoTraversal(t *testing.T) {
+	server, vaultClient := newTestServer(t)
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	seen := map[string]bool{}
+	for i, value := range []string{"v1", "v1", "v2"} {
+		encrypted, err := newService.Modify([]byte(value))
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		// Outside of traversals every value gets a fresh data key.
+		if server.Requests("datakey") != i+1 {
+			t.Fatal("expected", i+1, "got", server.Requests("datakey"))
+		}
+		k := wrappedKey(t, encrypted)
+		if seen[k] {
+			t.Fatal("expected", "new data key", "got", k)
+		}
+		seen[k] = true
+	}
+}
+
+func Test_Vault_Envelope_Encrypt_Service_Modify_Settings(t *testing.T) {
+	testCases := []struct {
+		Bits            int
+		Context         []byte
+		ExpectedBits    float64
+		ExpectedContext string
+	}{
+		// Test case 1, bits default to 256 and no context is sent.
+		{
+			Bits:            0,
+			Context:         nil,
+			ExpectedBits:    256,
+			ExpectedContext: "",
+		},
+		// Test case 2, 128 bit data keys.
+		{
+			Bits:            128,
+			Context:         nil,
+			ExpectedBits:    128,
+			ExpectedContext: "",
+		},
+		// Test case 3, 256 bit data keys.
+		{
+			Bits:            256,
+			Context:         nil,
+			ExpectedBits:    256,
+			ExpectedContext: "",
+		},
+		// Test case 4, the context is sent base64 encoded.
+		{
+			Bits:            0,
+			Context:         []byte("tenant-a"),
+			ExpectedBits:    256,
+			ExpectedContext: base64.StdEncoding.EncodeToString([]byte("tenant-a")),
+		},
+	}
+
+	for i, tc := range testCases {
+		server, vaultClient := newTestServer(t)
+
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.Bits = tc.Bits
+		config.Context = tc.Context
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		_, err = newService.Modify([]byte("foo"))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		request, ok := server.LastRequest("datakey")
+		if !ok {
+			t.Fatal("test", i+1, "expected", true, "got", ok)
+		}
+		if request.Path != "datakey/plaintext/foo" {
+			t.Fatal("test", i+1, "expected", "datakey/plaintext/foo", "got", request.Path)
+		}
+		if request.Data["bits"] != tc.ExpectedBits {
+			t.Fatal("test", i+1, "expected", tc.ExpectedBits, "got", request.Data["bits"])
+		}
+		context, ok := request.Data["context"]
+		if tc.ExpectedContext == "" {
+			if ok {
+				t.Fatal("test", i+1, "expected", nil, "got", context)
+			}
+		} else if context != tc.ExpectedContext {
+			t.Fatal("test", i+1, "expected", tc.ExpectedContext, "got", context)
+		}
+	}
+}
+
+func Test_Vault_Envelope_Encrypt_New_Error(t *testing.T) {
+	testCases := []struct {
+		Bits int
+	}{
+		// Test case 1, bits must not be negative.
+		{
+			Bits: -1,
+		},
+		// Test case 2, 192 bit data keys are not supported.
+		{
+			Bits: 192,
+		},
+		// Test case 3, 512 bit data keys are not supported.
+		{
+			Bits: 512,
+		},
+	}
+
+	_, vaultClient := newTestServer(t)
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.Bits = tc.Bits
+		_, err := New(config)
+		if !IsInvalidConfig(err) {
+			t.Fatal("test", i+1, "expected", true, "got", false)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package hmac
+
+import (
+	"encoding/base64"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+// Config represents the configuration used to create a new vault HMAC value
+// modifier.
+type Config struct {
+	VaultClient *vaultclient.Client
+	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// Algorithm is the hash algorithm used to compute HMACs, e.g. "sha2-256"
+	// or "sha2-512". When empty the Vault default is used.
+	Algorithm string
+	// KeyVersion pins the version of the transit key used to compute HMACs.
+	// When 0 the latest version is used.
+	KeyVersion int
+}
+
+// DefaultConfig provides a default configuration to create a new vault HMAC
+// value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		MountPath: "transit",
+	}
+}
+
+// New creates a new configured vault HMAC value modifier.
+func New(config Config) (*Service, error) {
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must be defined")
+	}
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
+	}
+	if config.KeyVersion < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.KeyVersion must not be negative")
+	}
+
+	path := transit.Path(config.MountPath, "hmac", config.Key)
+	if config.Algorithm != "" {
+		path += "/" + config.Algorithm
+	}
+
+	newService := &Service{
+		vaultClient: vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:        path,
+		keyVersion:  config.KeyVersion,
+	}
+
+	return newService, nil
+}
+
+// Service implements the vault HMAC value modifier.
+type Service struct {
+	vaultClient *vaultapi.Client
+	path        string
+	keyVersion  int
+}
+
+// Modify replaces the given value with its HMAC of the form
+// "vault:v<version>:<hmac>". The same value always results in the same HMAC
+// given the same transit key version, which allows to compare secrets without
+// revealing them. Empty values are returned as they are.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	data := map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(value),
+	}
+	if s.keyVersion != 0 {
+		data["key_version"] = s.keyVersion
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	hmac, err := vaultapi.String(secret, "hmac")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return []byte(hmac), nil
+}
@@ -0,0 +1,121 @@
+package hmac
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func Test_Vault_HMAC_Service_Modify(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	first, err := newService.Modify([]byte("bar"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(first), "vault:v1:") {
+		t.Fatal("expected", "vault:v1:", "got", string(first))
+	}
+
+	// The same value always results in the same HMAC.
+	second, err := newService.Modify([]byte("bar"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(first) != string(second) {
+		t.Fatal("expected", string(first), "got", string(second))
+	}
+
+	other, err := newService.Modify([]byte("baz"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(first) == string(other) {
+		t.Fatal("expected", "different HMACs", "got", string(other))
+	}
+
+	empty, err := newService.Modify([]byte(""))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(empty) != "" {
+		t.Fatal("expected", "", "got", string(empty))
+	}
+	if server.Requests("hmac") != 3 {
+		t.Fatal("expected", 3, "got", server.Requests("hmac"))
+	}
+}
+
+func Test_Vault_HMAC_Service_Modify_Settings(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	_, err = server.RotateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	config.Algorithm = "sha2-512"
+	config.KeyVersion = 1
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	modified, err := newService.Modify([]byte("bar"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(modified), "vault:v1:") {
+		t.Fatal("expected", "vault:v1:", "got", string(modified))
+	}
+
+	request, ok := server.LastRequest("hmac")
+	if !ok {
+		t.Fatal("expected", true, "got", ok)
+	}
+	if request.Path != "hmac/foo/sha2-512" {
+		t.Fatal("expected", "hmac/foo/sha2-512", "got", request.Path)
+	}
+	if request.Data["key_version"] != float64(1) {
+		t.Fatal("expected", 1, "got", request.Data["key_version"])
+	}
+}
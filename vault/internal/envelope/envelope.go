@@ -0,0 +1,104 @@
+// Package envelope implements the format of values encrypted locally using a
+// data key, which is stored alongside the encrypted value wrapped by the Vault
+// transit secrets engine.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Prefix is the prefix of all envelope encrypted values, including the format
+// version.
+const Prefix = "vaultenv:v1:"
+
+// Seal encrypts the given plain text with the given data key using AES-GCM and
+// returns the envelope of the form
+// "vaultenv:v1:<base64 wrapped key>:<base64 nonce and cipher text>".
+func Seal(key []byte, wrappedKey string, plainText []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plainText, []byte(wrappedKey))
+
+	var b strings.Builder
+	b.WriteString(Prefix)
+	b.WriteString(base64.StdEncoding.EncodeToString([]byte(wrappedKey)))
+	b.WriteString(":")
+	b.WriteString(base64.StdEncoding.EncodeToString(sealed))
+
+	return []byte(b.String()), nil
+}
+
+// Split returns the wrapped data key and the sealed cipher text of the given
+// envelope.
+func Split(value []byte) (string, []byte, error) {
+	s := string(value)
+	if !strings.HasPrefix(s, Prefix) {
+		return "", nil, microerror.Maskf(invalidEnvelopeError, "value must start with %q", Prefix)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(s, Prefix), ":")
+	if len(parts) != 2 {
+		return "", nil, microerror.Maskf(invalidEnvelopeError, "value must be of the form %s<wrapped key>:<cipher text>", Prefix)
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil || len(wrappedKey) == 0 {
+		return "", nil, microerror.Maskf(invalidEnvelopeError, "wrapped key must be base64 encoded")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, microerror.Maskf(invalidEnvelopeError, "cipher text must be base64 encoded")
+	}
+
+	return string(wrappedKey), sealed, nil
+}
+
+// Open decrypts the given sealed cipher text, as returned by Split, with the
+// given data key. The wrapped key is authenticated as well, so that cipher
+// texts cannot be moved between envelopes.
+func Open(key []byte, wrappedKey string, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, microerror.Maskf(invalidEnvelopeError, "cipher text must contain a nonce")
+	}
+
+	nonce, cipherText := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plainText, err := aead.Open(nil, nonce, cipherText, []byte(wrappedKey))
+	if err != nil {
+		return nil, microerror.Maskf(invalidEnvelopeError, "%s", err.Error())
+	}
+
+	return plainText, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return aead, nil
+}
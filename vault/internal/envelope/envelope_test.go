@@ -0,0 +1,70 @@
+package envelope
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_Envelope_Seal_Open(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	wrappedKey := "vault:v1:d3JhcHBlZA=="
+	expected := []byte("hello world")
+
+	sealed, err := Seal(key, wrappedKey, expected)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(sealed), Prefix) {
+		t.Fatal("expected", Prefix, "got", string(sealed))
+	}
+
+	w, c, err := Split(sealed)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if w != wrappedKey {
+		t.Fatal("expected", wrappedKey, "got", w)
+	}
+
+	plainText, err := Open(key, w, c)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(plainText) != string(expected) {
+		t.Fatal("expected", string(expected), "got", string(plainText))
+	}
+
+	// The wrapped key is authenticated, so that cipher texts cannot be moved
+	// to another envelope.
+	_, err = Open(key, "vault:v2:b3RoZXI=", c)
+	if !IsInvalidEnvelope(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
+
+func Test_Envelope_Split_Error(t *testing.T) {
+	testCases := []struct {
+		Value string
+	}{
+		// Test case 1, the prefix is required.
+		{
+			Value: "vault:v1:Zm9v",
+		},
+		// Test case 2, the cipher text is required.
+		{
+			Value: Prefix + "Zm9v",
+		},
+		// Test case 3, the wrapped key must be base64 encoded.
+		{
+			Value: Prefix + "%%%:Zm9v",
+		},
+	}
+
+	for i, tc := range testCases {
+		_, _, err := Split([]byte(tc.Value))
+		if !IsInvalidEnvelope(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package envelope
+
+import "github.com/giantswarm/microerror"
+
+var invalidEnvelopeError = &microerror.Error{
+	Kind: "invalidEnvelopeError",
+}
+
+// IsInvalidEnvelope asserts invalidEnvelopeError.
+func IsInvalidEnvelope(err error) bool {
+	return microerror.Cause(err) == invalidEnvelopeError
+}
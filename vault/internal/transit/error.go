@@ -0,0 +1,17 @@
+package transit
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+var invalidCiphertextError = &microerror.Error{
+	Kind: "invalidCiphertextError",
+}
+
+// IsInvalidCiphertext asserts invalidCiphertextError, which is returned for
+// malformed cipher texts, as well as cipher texts rejected by Vault.
+func IsInvalidCiphertext(err error) bool {
+	return microerror.Cause(err) == invalidCiphertextError || vaultapi.IsInvalidCiphertext(err)
+}
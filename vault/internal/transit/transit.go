@@ -0,0 +1,54 @@
+// Package transit implements the functionality shared by the value modifiers
+// using the Vault transit secrets engine, like building request paths and
+// validating cipher texts.
+package transit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// DefaultMountPath is the path the transit secrets engine is mounted at by
+// default.
+const DefaultMountPath = "transit"
+
+// Path returns the request path of the given transit endpoint and key, e.g.
+// "/transit/encrypt/my-key". An empty mount path falls back to
+// DefaultMountPath.
+func Path(mountPath, endpoint, key string) string {
+	mountPath = strings.Trim(mountPath, "/")
+	if mountPath == "" {
+		mountPath = DefaultMountPath
+	}
+
+	return fmt.Sprintf("/%s/%s/%s", mountPath, endpoint, key)
+}
+
+// KeyVersion validates that the given cipher text is of the form
+// "vault:v<version>:<cipher text>" and returns its key version.
+func KeyVersion(cipherText string) (int, error) {
+	version, _, err := split(cipherText, "vault")
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return version, nil
+}
+
+// split splits the given value of the form "<prefix>:v<version>:<rest>".
+func split(value string, prefix string) (int, string, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] != prefix || !strings.HasPrefix(parts[1], "v") || parts[2] == "" {
+		return 0, "", microerror.Maskf(invalidCiphertextError, "cipher text must be of the form %s:v<version>:<cipher text>", prefix)
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+	if err != nil || version < 1 {
+		return 0, "", microerror.Maskf(invalidCiphertextError, "cipher text must be of the form %s:v<version>:<cipher text>", prefix)
+	}
+
+	return version, parts[2], nil
+}
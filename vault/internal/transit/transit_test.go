@@ -0,0 +1,50 @@
+package transit
+
+import (
+	"testing"
+)
+
+func Test_Transit_KeyVersion(t *testing.T) {
+	testCases := []struct {
+		CipherText   string
+		Expected     int
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, the key version is returned.
+		{
+			CipherText: "vault:v12:Zm9v",
+			Expected:   12,
+		},
+		// Test case 2, the prefix is required.
+		{
+			CipherText:   "v12:Zm9v",
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 3, the version must be numeric.
+		{
+			CipherText:   "vault:vx:Zm9v",
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 4, the cipher text must not be empty.
+		{
+			CipherText:   "vault:v1:",
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+	}
+
+	for i, tc := range testCases {
+		version, err := KeyVersion(tc.CipherText)
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if version != tc.Expected {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", version)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package vaultapi
+
+import "github.com/giantswarm/microerror"
+
+var invalidCiphertextError = &microerror.Error{
+	Kind: "invalidCiphertextError",
+}
+
+// IsInvalidCiphertext asserts invalidCiphertextError, which is returned when
+// Vault rejects a cipher text.
+func IsInvalidCiphertext(err error) bool {
+	return microerror.Cause(err) == invalidCiphertextError
+}
+
+var invalidResponseError = &microerror.Error{
+	Kind: "invalidResponseError",
+}
+
+// IsInvalidResponse asserts invalidResponseError.
+func IsInvalidResponse(err error) bool {
+	return microerror.Cause(err) == invalidResponseError
+}
+
+var keyNotFoundError = &microerror.Error{
+	Kind: "keyNotFoundError",
+}
+
+// IsKeyNotFound asserts keyNotFoundError.
+func IsKeyNotFound(err error) bool {
+	return microerror.Cause(err) == keyNotFoundError
+}
+
+var permissionDeniedError = &microerror.Error{
+	Kind: "permissionDeniedError",
+}
+
+// IsPermissionDenied asserts permissionDeniedError.
+func IsPermissionDenied(err error) bool {
+	return microerror.Cause(err) == permissionDeniedError
+}
+
+var vaultUnavailableError = &microerror.Error{
+	Kind: "vaultUnavailableError",
+}
+
+// IsVaultUnavailable asserts vaultUnavailableError.
+func IsVaultUnavailable(err error) bool {
+	return microerror.Cause(err) == vaultUnavailableError
+}
@@ -0,0 +1,188 @@
+// Package vaultapi implements the functionality shared by all packages talking
+// to Vault, like sending requests, validating responses and classifying errors
+// returned by Vault.
+package vaultapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+// Client sends requests to Vault using the given Vault client, scoped to the
+// given namespace. The given Vault client is used as it is at the time of each
+// request, so that tokens set later on, e.g. by vault/auth, are used as well.
+type Client struct {
+	vaultClient *vaultclient.Client
+	namespace   string
+}
+
+// NewClient returns a client sending requests to Vault using the given Vault
+// client. When the namespace is empty the namespace of the given Vault client
+// is used.
+func NewClient(vaultClient *vaultclient.Client, namespace string) *Client {
+	c := &Client{
+		vaultClient: vaultClient,
+		namespace:   namespace,
+	}
+
+	return c
+}
+
+// Write sends the given data to the given path and returns the secret Vault
+// responded with. Errors are classified so that they can be asserted using
+// IsVaultUnavailable, IsKeyNotFound, IsPermissionDenied and
+// IsInvalidCiphertext. Responses without any secret are treated as missing
+// keys, since this is how Vault answers requests for unknown paths.
+func (c *Client) Write(path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	secret, err := c.WriteWithContext(context.Background(), path, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return secret, nil
+}
+
+// WriteWithContext works like Write, but uses the given context for the
+// request.
+func (c *Client) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	vaultClient, err := c.client()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	secret, err := vaultClient.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		return nil, microerror.Mask(classify(err))
+	}
+	if secret == nil {
+		return nil, microerror.Maskf(keyNotFoundError, "Vault returned no data for %q", path)
+	}
+
+	return secret, nil
+}
+
+// Read reads the given path with the given query parameters and returns the
+// secret Vault responded with. Errors are classified the same way as by Write.
+func (c *Client) Read(path string, params map[string][]string) (*vaultclient.Secret, error) {
+	vaultClient, err := c.client()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	secret, err := vaultClient.Logical().ReadWithData(path, params)
+	if err != nil {
+		return nil, microerror.Mask(classify(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, microerror.Maskf(keyNotFoundError, "Vault returned no data for %q", path)
+	}
+
+	return secret, nil
+}
+
+// client returns the Vault client to send a single request with. The
+// namespace is set on a clone made for every request, since
+// vaultclient.Client.WithNamespace would keep the token the given Vault client
+// had at the time it was called.
+func (c *Client) client() (*vaultclient.Client, error) {
+	if c.namespace == "" {
+		return c.vaultClient, nil
+	}
+
+	vaultClient, err := c.vaultClient.CloneWithHeaders()
+	if err != nil {
+		return nil, microerror.Maskf(vaultUnavailableError, "%s", err.Error())
+	}
+	vaultClient.SetToken(c.vaultClient.Token())
+	vaultClient.SetNamespace(c.namespace)
+
+	return vaultClient, nil
+}
+
+// String returns the string field of the given secret.
+func String(secret *vaultclient.Secret, field string) (string, error) {
+	if secret == nil || secret.Data == nil {
+		return "", microerror.Maskf(invalidResponseError, "Vault response must contain data")
+	}
+
+	v, ok := secret.Data[field]
+	if !ok || v == nil {
+		return "", microerror.Maskf(invalidResponseError, "Vault response must contain field %q", field)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", microerror.Maskf(invalidResponseError, "Vault response field %q must be a string but is %T", field, v)
+	}
+	return s, nil
+}
+
+// Bool returns the boolean field of the given secret.
+func Bool(secret *vaultclient.Secret, field string) (bool, error) {
+	if secret == nil || secret.Data == nil {
+		return false, microerror.Maskf(invalidResponseError, "Vault response must contain data")
+	}
+
+	v, ok := secret.Data[field].(bool)
+	if !ok {
+		return false, microerror.Maskf(invalidResponseError, "Vault response field %q must be a boolean", field)
+	}
+
+	return v, nil
+}
+
+// Int returns the integer field of the given secret.
+func Int(secret *vaultclient.Secret, field string) (int, error) {
+	if secret == nil || secret.Data == nil {
+		return 0, microerror.Maskf(invalidResponseError, "Vault response must contain data")
+	}
+
+	var i int64
+	var err error
+	switch v := secret.Data[field].(type) {
+	case json.Number:
+		i, err = v.Int64()
+	case float64:
+		i, err = int64(v), nil
+		if float64(i) != v {
+			err = fmt.Errorf("%v is no integer", v)
+		}
+	default:
+		err = fmt.Errorf("%T is no integer", v)
+	}
+	if err != nil {
+		return 0, microerror.Maskf(invalidResponseError, "Vault response field %q must be an integer: %s", field, err.Error())
+	}
+
+	return int(i), nil
+}
+
+func classify(err error) error {
+	var responseError *vaultclient.ResponseError
+	if !errors.As(err, &responseError) {
+		// Errors not returned by Vault itself are caused by failing to talk
+		// to Vault, e.g. because of connection or TLS issues.
+		return microerror.Maskf(vaultUnavailableError, "%s", err.Error())
+	}
+
+	message := strings.ToLower(strings.Join(responseError.Errors, "; "))
+
+	switch {
+	case responseError.StatusCode == http.StatusForbidden:
+		return microerror.Maskf(permissionDeniedError, "%s", err.Error())
+	case responseError.StatusCode == http.StatusTooManyRequests || responseError.StatusCode >= http.StatusInternalServerError:
+		return microerror.Maskf(vaultUnavailableError, "%s", err.Error())
+	case responseError.StatusCode == http.StatusNotFound || strings.Contains(message, "key not found"):
+		return microerror.Maskf(keyNotFoundError, "%s", err.Error())
+	case strings.Contains(message, "invalid ciphertext") || strings.Contains(message, "message authentication failed"):
+		return microerror.Maskf(invalidCiphertextError, "%s", err.Error())
+	}
+
+	return err
+}
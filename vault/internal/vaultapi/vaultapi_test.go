@@ -0,0 +1,189 @@
+package vaultapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+func Test_VaultAPI_Write_Error(t *testing.T) {
+	testCases := []struct {
+		StatusCode   int
+		Body         string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, missing permissions are detected.
+		{
+			StatusCode:   http.StatusForbidden,
+			Body:         `{"errors":["permission denied"]}`,
+			ErrorMatcher: IsPermissionDenied,
+		},
+		// Test case 2, missing keys are detected.
+		{
+			StatusCode:   http.StatusBadRequest,
+			Body:         `{"errors":["encryption key not found"]}`,
+			ErrorMatcher: IsKeyNotFound,
+		},
+		// Test case 3, unknown paths are treated as missing keys.
+		{
+			StatusCode:   http.StatusNotFound,
+			Body:         ``,
+			ErrorMatcher: IsKeyNotFound,
+		},
+		// Test case 4, rejected cipher texts are detected.
+		{
+			StatusCode:   http.StatusBadRequest,
+			Body:         `{"errors":["invalid ciphertext: no prefix"]}`,
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 5, a sealed Vault is unavailable.
+		{
+			StatusCode:   http.StatusServiceUnavailable,
+			Body:         `{"errors":["Vault is sealed"]}`,
+			ErrorMatcher: IsVaultUnavailable,
+		},
+		// Test case 6, responses without data are treated as missing keys.
+		{
+			StatusCode:   http.StatusNoContent,
+			Body:         ``,
+			ErrorMatcher: IsKeyNotFound,
+		},
+	}
+
+	for i, tc := range testCases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.StatusCode)
+			_, _ = w.Write([]byte(tc.Body))
+		}))
+
+		vaultClient, err := vaultclient.NewClient(&vaultclient.Config{Address: server.URL})
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		vaultClient.SetMaxRetries(0)
+
+		_, err = NewClient(vaultClient, "").Write("/transit/encrypt/foo", map[string]interface{}{})
+		if !tc.ErrorMatcher(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+
+		server.Close()
+	}
+
+	// Vault cannot be reached at all.
+	{
+		vaultClient, err := vaultclient.NewClient(&vaultclient.Config{Address: "http://127.0.0.1:0"})
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+		vaultClient.SetMaxRetries(0)
+
+		_, err = NewClient(vaultClient, "").Write("/transit/encrypt/foo", map[string]interface{}{})
+		if !IsVaultUnavailable(err) {
+			t.Fatal("expected", true, "got", err)
+		}
+	}
+}
+
+func Test_VaultAPI_String(t *testing.T) {
+	testCases := []struct {
+		Secret       *vaultclient.Secret
+		Expected     string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, string fields are returned.
+		{
+			Secret: &vaultclient.Secret{
+				Data: map[string]interface{}{
+					"ciphertext": "vault:v1:Zm9v",
+				},
+			},
+			Expected: "vault:v1:Zm9v",
+		},
+		// Test case 2, missing secrets are rejected.
+		{
+			Secret:       nil,
+			ErrorMatcher: IsInvalidResponse,
+		},
+		// Test case 3, missing fields are rejected.
+		{
+			Secret: &vaultclient.Secret{
+				Data: map[string]interface{}{},
+			},
+			ErrorMatcher: IsInvalidResponse,
+		},
+		// Test case 4, fields of other types are rejected.
+		{
+			Secret: &vaultclient.Secret{
+				Data: map[string]interface{}{
+					"ciphertext": 1,
+				},
+			},
+			ErrorMatcher: IsInvalidResponse,
+		},
+	}
+
+	for i, tc := range testCases {
+		s, err := String(tc.Secret, "ciphertext")
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if s != tc.Expected {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", s)
+		}
+	}
+}
+
+func Test_VaultAPI_Int(t *testing.T) {
+	testCases := []struct {
+		Secret       *vaultclient.Secret
+		Expected     int
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, JSON numbers are returned.
+		{
+			Secret:   &vaultclient.Secret{Data: map[string]interface{}{"latest_version": json.Number("3")}},
+			Expected: 3,
+		},
+		// Test case 2, integral floats are returned.
+		{
+			Secret:   &vaultclient.Secret{Data: map[string]interface{}{"latest_version": float64(3)}},
+			Expected: 3,
+		},
+		// Test case 3, fractional numbers are rejected.
+		{
+			Secret:       &vaultclient.Secret{Data: map[string]interface{}{"latest_version": float64(1.5)}},
+			ErrorMatcher: IsInvalidResponse,
+		},
+		// Test case 4, missing fields are rejected.
+		{
+			Secret:       &vaultclient.Secret{Data: map[string]interface{}{}},
+			ErrorMatcher: IsInvalidResponse,
+		},
+	}
+
+	for i, tc := range testCases {
+		v, err := Int(tc.Secret, "latest_version")
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if v != tc.Expected {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", v)
+		}
+	}
+}
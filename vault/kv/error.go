@@ -0,0 +1,55 @@
+package kv
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+var fieldNotFoundError = &microerror.Error{
+	Kind: "fieldNotFoundError",
+}
+
+// IsFieldNotFound asserts fieldNotFoundError.
+func IsFieldNotFound(err error) bool {
+	return microerror.Cause(err) == fieldNotFoundError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidReferenceError = &microerror.Error{
+	Kind: "invalidReferenceError",
+}
+
+// IsInvalidReference asserts invalidReferenceError.
+func IsInvalidReference(err error) bool {
+	return microerror.Cause(err) == invalidReferenceError
+}
+
+var secretNotFoundError = &microerror.Error{
+	Kind: "secretNotFoundError",
+}
+
+// IsSecretNotFound asserts secretNotFoundError, which is also returned when
+// the referenced secret or KV mount does not exist.
+func IsSecretNotFound(err error) bool {
+	return microerror.Cause(err) == secretNotFoundError || vaultapi.IsKeyNotFound(err)
+}
+
+// IsPermissionDenied asserts that the Vault token lacks permissions.
+func IsPermissionDenied(err error) bool {
+	return vaultapi.IsPermissionDenied(err)
+}
+
+// IsVaultUnavailable asserts that Vault could not be reached or failed to
+// process the request.
+func IsVaultUnavailable(err error) bool {
+	return vaultapi.IsVaultUnavailable(err)
+}
@@ -0,0 +1,211 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+// DefaultPattern matches references of the form
+// "vault:<path>#<field>[@<version>]", e.g. "vault:secret/data/app#password" or
+// "vault:secret/data/app#password@3".
+const DefaultPattern = `^vault:(?P<path>[^#]+)#(?P<field>[^@]+)(?:@(?P<version>[0-9]+))?$`
+
+// Config represents the configuration used to create a new vault KV resolving
+// value modifier.
+type Config struct {
+	VaultClient *vaultclient.Client
+	// Namespace is the Vault Enterprise namespace the KV secrets engine lives
+	// in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// Pattern is the regular expression references are recognised by. It
+	// must define the named groups "path" and "field" and may define the named
+	// group "version". It defaults to DefaultPattern, which only matches whole
+	// values. Every match is replaced, so that patterns which are not anchored
+	// resolve references embedded in larger values, e.g.
+	// "https://${secret/data/app:token}@host".
+	Pattern string
+	// KVVersion is the version of the KV secrets engine, either 1 or 2. It
+	// defaults to 2. Paths of references are used as they are, which means
+	// paths of KV version 2 must contain the "data" segment, e.g.
+	// "secret/data/app". Versions can only be pinned with KV version 2.
+	KVVersion int
+}
+
+// DefaultConfig provides a default configuration to create a new vault KV
+// resolving value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		Pattern:   DefaultPattern,
+		KVVersion: 2,
+	}
+}
+
+// New creates a new configured vault KV resolving value modifier.
+func New(config Config) (*Service, error) {
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must be defined")
+	}
+
+	pattern := config.Pattern
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pattern must be a valid regular expression: %s", err.Error())
+	}
+	if re.SubexpIndex("path") == -1 || re.SubexpIndex("field") == -1 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Pattern must define the named groups %q and %q", "path", "field")
+	}
+
+	kvVersion := config.KVVersion
+	switch kvVersion {
+	case 0:
+		kvVersion = 2
+	case 1, 2:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.KVVersion must be 1 or 2")
+	}
+
+	newService := &Service{
+		vaultClient: vaultapi.NewClient(config.VaultClient, config.Namespace),
+		pattern:     re,
+		kvVersion:   kvVersion,
+	}
+
+	return newService, nil
+}
+
+// Service implements the vault KV resolving value modifier. It implements
+// valuemodifier.TraversalValueModifier, so that every secret is only read once
+// per traversed document.
+type Service struct {
+	vaultClient *vaultapi.Client
+	pattern     *regexp.Regexp
+	kvVersion   int
+
+	// secrets holds the secrets read during the current traversal, keyed by
+	// their path and version. It is nil outside of traversals.
+	secrets map[string]map[string]interface{}
+}
+
+// BeginTraversal makes all values modified until EndTraversal share the
+// secrets read from Vault.
+func (s *Service) BeginTraversal() error {
+	s.secrets = map[string]map[string]interface{}{}
+
+	return nil
+}
+
+// EndTraversal forgets the secrets read during the current traversal.
+func (s *Service) EndTraversal() {
+	s.secrets = nil
+}
+
+// Modify replaces all references found in the given value with the value of
+// the referenced field of the referenced KV secret. String fields are inserted
+// as they are, all other fields are inserted JSON encoded. Values without any
+// reference are returned as they are.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	matches := s.pattern.FindAllSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, nil
+	}
+
+	var modified []byte
+	var last int
+	for _, match := range matches {
+		b, err := s.resolve(value, match)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		modified = append(modified, value[last:match[0]]...)
+		modified = append(modified, b...)
+		last = match[1]
+	}
+	modified = append(modified, value[last:]...)
+
+	return modified, nil
+}
+
+// resolve returns the value of the field referenced by the given match of the
+// configured pattern within the given value.
+func (s *Service) resolve(value []byte, match []int) ([]byte, error) {
+	group := func(name string) string {
+		i := s.pattern.SubexpIndex(name)
+		if i == -1 || match[2*i] == -1 {
+			return ""
+		}
+		return string(value[match[2*i]:match[2*i+1]])
+	}
+
+	path := strings.Trim(group("path"), "/")
+	field := group("field")
+	version := group("version")
+
+	data, err := s.read(path, version)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	v, ok := data[field]
+	if !ok {
+		return nil, microerror.Maskf(fieldNotFoundError, "secret %q must contain field %q", path, field)
+	}
+	if str, ok := v.(string); ok {
+		return []byte(str), nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+func (s *Service) read(path string, version string) (map[string]interface{}, error) {
+	key := fmt.Sprintf("%s@%s", path, version)
+	if data, ok := s.secrets[key]; ok {
+		return data, nil
+	}
+
+	var params map[string][]string
+	if version != "" {
+		if s.kvVersion != 2 {
+			return nil, microerror.Maskf(invalidReferenceError, "versions of secret %q can only be pinned with KV version 2", path)
+		}
+		params = map[string][]string{
+			"version": {version},
+		}
+	}
+
+	secret, err := s.vaultClient.Read(path, params)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	data := secret.Data
+	if s.kvVersion == 2 {
+		data, _ = secret.Data["data"].(map[string]interface{})
+		if data == nil {
+			// KV version 2 returns no data for deleted and destroyed
+			// versions.
+			return nil, microerror.Maskf(secretNotFoundError, "secret %q must not be deleted", path)
+		}
+	}
+
+	if s.secrets != nil {
+		s.secrets[key] = data
+	}
+
+	return data, nil
+}
@@ -0,0 +1,191 @@
+package kv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+func Test_Vault_KV_Service_Modify(t *testing.T) {
+	testCases := []struct {
+		KVVersion    int
+		Pattern      string
+		Value        string
+		Expected     string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, fields of KV version 2 secrets are resolved.
+		{
+			Value:    "vault:secret/data/app#password",
+			Expected: "latest",
+		},
+		// Test case 2, versions of KV version 2 secrets are pinned.
+		{
+			Value:    "vault:secret/data/app#password@1",
+			Expected: "first",
+		},
+		// Test case 3, fields other than strings are JSON encoded.
+		{
+			Value:    "vault:secret/data/app#port",
+			Expected: "8080",
+		},
+		// Test case 4, fields of KV version 1 secrets are resolved.
+		{
+			KVVersion: 1,
+			Value:     "vault:kv/app#password",
+			Expected:  "v1",
+		},
+		// Test case 5, values which are no references are left untouched.
+		{
+			Value:    "secret/data/app#password",
+			Expected: "secret/data/app#password",
+		},
+		// Test case 6, custom reference syntax is supported.
+		{
+			Pattern:  `^\$\{(?P<path>[^:]+):(?P<field>[^}]+)\}$`,
+			Value:    "${secret/data/app:password}",
+			Expected: "latest",
+		},
+		// Test case 7, references embedded in larger values are replaced,
+		// keeping the rest of the value.
+		{
+			Pattern:  `\$\{(?P<path>[^:}]+):(?P<field>[^}]+)\}`,
+			Value:    "https://${secret/data/app:password}@host:${secret/data/app:port}",
+			Expected: "https://latest@host:8080",
+		},
+		// Test case 8, values matching the default pattern only in parts are
+		// left untouched.
+		{
+			Value:    "https://vault:secret/data/app#password",
+			Expected: "https://vault:secret/data/app#password",
+		},
+		// Test case 9, missing fields are rejected.
+		{
+			Value:        "vault:secret/data/app#missing",
+			ErrorMatcher: IsFieldNotFound,
+		},
+		// Test case 10, missing secrets are rejected.
+		{
+			Value:        "vault:secret/data/missing#password",
+			ErrorMatcher: IsSecretNotFound,
+		},
+		// Test case 11, versions cannot be pinned with KV version 1.
+		{
+			KVVersion:    1,
+			Value:        "vault:kv/app#password@1",
+			ErrorMatcher: IsInvalidReference,
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/secret/data/app" && r.URL.Query().Get("version") == "1":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"first"}}}`))
+		case r.URL.Path == "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"latest","port":8080}}}`))
+		case r.URL.Path == "/v1/kv/app":
+			_, _ = w.Write([]byte(`{"data":{"password":"v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	vaultClient, err := vaultclient.NewClient(&vaultclient.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.KVVersion = tc.KVVersion
+		config.Pattern = tc.Pattern
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := newService.Modify([]byte(tc.Value))
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != tc.Expected {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", string(modified))
+		}
+	}
+}
+
+func Test_Vault_KV_Service_Modify_Cache(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"data":{"data":{"user":"alice","password":"secret"}}}`))
+	}))
+	defer server.Close()
+
+	vaultClient, err := vaultclient.NewClient(&vaultclient.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Secrets are read once per traversal.
+	for i := 0; i < 2; i++ {
+		err = newService.BeginTraversal()
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+		for _, v := range []string{"vault:secret/data/app#user", "vault:secret/data/app#password"} {
+			_, err := newService.Modify([]byte(v))
+			if err != nil {
+				t.Fatal("expected", nil, "got", err)
+			}
+		}
+		newService.EndTraversal()
+
+		if requests != i+1 {
+			t.Fatal("expected", i+1, "got", requests)
+		}
+	}
+
+	// Secrets are not cached outside of traversals.
+	for _, v := range []string{"vault:secret/data/app#user", "vault:secret/data/app#password"} {
+		_, err := newService.Modify([]byte(v))
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+	if requests != 4 {
+		t.Fatal("expected", 4, "got", requests)
+	}
+}
+
+func Test_Vault_KV_New_Error(t *testing.T) {
+	vaultClient, err := vaultclient.NewClient(&vaultclient.Config{Address: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Pattern = `^vault:(?P<path>.+)$`
+	_, err = New(config)
+	if !IsInvalidConfig(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+}
@@ -0,0 +1,225 @@
+package rewrap
+
+import (
+	"encoding/base64"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+// Config represents the configuration used to create a new vault rewrapping
+// value modifier.
+type Config struct {
+	VaultClient *vaultclient.Client
+	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// KeyVersion is the version of the transit key values are rewrapped to.
+	// When 0 the latest version is used.
+	KeyVersion int
+	// MinVersion is the minimum version of the transit key values must be
+	// encrypted with. Values already encrypted with MinVersion or later are
+	// returned as they are, without calling Vault. When 0 it defaults to
+	// KeyVersion. When both are 0 the latest version of the transit key is
+	// read from Vault once per traversal, which requires read access to
+	// "<mount path>/keys/<key>".
+	MinVersion int
+	// Context is the context used to derive the encryption key of transit keys
+	// created with key derivation enabled. Context must not be used together
+	// with PathContext.
+	Context []byte
+	// PathContext uses the path of each traversed value as context. It must be
+	// provided when values were encrypted using config.PathContext of the vault
+	// encrypting value modifier. PathContext requires the value modifier to be
+	// used via Traverse or Rotate.
+	PathContext bool
+}
+
+// DefaultConfig provides a default configuration to create a new vault
+// rewrapping value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		MountPath: "transit",
+	}
+}
+
+// New creates a new configured vault rewrapping value modifier.
+func New(config Config) (*Service, error) {
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must be defined")
+	}
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
+	}
+	if config.KeyVersion < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.KeyVersion must not be negative")
+	}
+	if config.MinVersion < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.MinVersion must not be negative")
+	}
+	if config.KeyVersion != 0 && config.MinVersion > config.KeyVersion {
+		return nil, microerror.Maskf(invalidConfigError, "config.MinVersion must not be greater than config.KeyVersion")
+	}
+	if len(config.Context) != 0 && config.PathContext {
+		return nil, microerror.Maskf(invalidConfigError, "config.Context must be empty when config.PathContext provided")
+	}
+
+	minVersion := config.MinVersion
+	if minVersion == 0 {
+		minVersion = config.KeyVersion
+	}
+
+	newService := &Service{
+		vaultClient: vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:        transit.Path(config.MountPath, "rewrap", config.Key),
+		keyPath:     transit.Path(config.MountPath, "keys", config.Key),
+		keyVersion:  config.KeyVersion,
+		minVersion:  minVersion,
+		context:     config.Context,
+		pathContext: config.PathContext,
+	}
+
+	return newService, nil
+}
+
+// Service implements the vault rewrapping value modifier. It implements
+// valuemodifier.TraversalValueModifier, so that the latest version of the
+// transit key is only read once per traversal.
+type Service struct {
+	vaultClient *vaultapi.Client
+	path        string
+	keyPath     string
+	keyVersion  int
+	minVersion  int
+	context     []byte
+	pathContext bool
+
+	// latestVersion is the latest version of the transit key read at the
+	// beginning of the current traversal. It is 0 outside of traversals and
+	// when config.MinVersion or config.KeyVersion is provided.
+	latestVersion int
+}
+
+// BeginTraversal reads the latest version of the transit key, unless the
+// version values are rewrapped to is configured.
+func (s *Service) BeginTraversal() error {
+	s.latestVersion = 0
+	if s.minVersion != 0 {
+		return nil
+	}
+
+	latestVersion, err := s.readLatestVersion()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	s.latestVersion = latestVersion
+
+	return nil
+}
+
+// EndTraversal forgets the latest version of the transit key.
+func (s *Service) EndTraversal() {
+	s.latestVersion = 0
+}
+
+// Modify rewraps the given transit cipher text, so that it is encrypted with
+// the configured version of the transit key. The plain text is never exposed
+// outside of Vault. Cipher texts already encrypted with the configured or the
+// latest version are returned as they are, so that rewrapping twice does not
+// change anything. Outside of traversals the latest version is read for every
+// value.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if s.pathContext {
+		return nil, microerror.Maskf(missingPathError, "value must be modified via ModifyPath when config.PathContext provided")
+	}
+
+	cipherText, err := s.rewrap(string(value), s.context)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return []byte(cipherText), nil
+}
+
+// ModifyPath works like Modify, but uses the given path as context when
+// config.PathContext is provided.
+func (s *Service) ModifyPath(path string, value []byte) ([]byte, error) {
+	context := s.context
+	if s.pathContext {
+		context = []byte(path)
+	}
+
+	cipherText, err := s.rewrap(string(value), context)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return []byte(cipherText), nil
+}
+
+func (s *Service) rewrap(cipherText string, context []byte) (string, error) {
+	version, err := transit.KeyVersion(cipherText)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	minVersion := s.minVersion
+	if minVersion == 0 {
+		minVersion = s.latestVersion
+	}
+	if minVersion == 0 {
+		minVersion, err = s.readLatestVersion()
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+	}
+	if version >= minVersion {
+		return cipherText, nil
+	}
+
+	data := map[string]interface{}{
+		"ciphertext": cipherText,
+	}
+	if s.keyVersion != 0 {
+		data["key_version"] = s.keyVersion
+	}
+	if len(context) != 0 {
+		data["context"] = base64.StdEncoding.EncodeToString(context)
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	rewrapped, err := vaultapi.String(secret, "ciphertext")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rewrapped, nil
+}
+
+func (s *Service) readLatestVersion() (int, error) {
+	secret, err := s.vaultClient.Read(s.keyPath, nil)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	latestVersion, err := vaultapi.Int(secret, "latest_version")
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	if latestVersion < 1 {
+		return 0, microerror.Maskf(vaultResponseError, "Vault response field %q must be positive", "latest_version")
+	}
+
+	return latestVersion, nil
+}
@@ -0,0 +1,249 @@
+package rewrap
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier"
+	"github.com/giantswarm/valuemodifier/vault/encrypt"
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func Test_Vault_Rewrap_Service_Modify_Skip(t *testing.T) {
+	testCases := []struct {
+		Value        string
+		ErrorMatcher func(error) bool
+	}{
+		// Test case 1, values at the minimum version are not rewrapped.
+		{
+			Value: "vault:v2:Zm9v",
+		},
+		// Test case 2, values beyond the minimum version are not rewrapped.
+		{
+			Value: "vault:v3:Zm9v",
+		},
+		// Test case 3, values which are no transit cipher texts are rejected.
+		{
+			Value:        "foo",
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+		// Test case 4, values with an invalid version are rejected.
+		{
+			Value:        "vault:vx:Zm9v",
+			ErrorMatcher: IsInvalidCiphertext,
+		},
+	}
+
+	// Vault is never called in the test cases below, so the client points to
+	// an address nothing listens on.
+	vaultClient, err := vaultclient.NewClient(&vaultclient.Config{Address: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.MinVersion = 2
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := newService.Modify([]byte(tc.Value))
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != tc.Value {
+			t.Fatal("test", i+1, "expected", tc.Value, "got", string(modified))
+		}
+	}
+}
+
+func Test_Vault_Rewrap_Service_Modify(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	secret, err := vaultClient.Logical().Write("/transit/encrypt/foo", map[string]interface{}{"plaintext": "Zm9v"})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	v1 := secret.Data["ciphertext"].(string)
+
+	_, err = server.RotateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	config.MinVersion = 2
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	v2, err := newService.Modify([]byte(v1))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(v2), "vault:v2:") {
+		t.Fatal("expected", "vault:v2:", "got", string(v2))
+	}
+	if server.Requests("rewrap") != 1 {
+		t.Fatal("expected", 1, "got", server.Requests("rewrap"))
+	}
+
+	// Rewrapping values already at the minimum version does not call Vault.
+	modified, err := newService.Modify(v2)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(modified) != string(v2) {
+		t.Fatal("expected", string(v2), "got", string(modified))
+	}
+	if server.Requests("rewrap") != 1 {
+		t.Fatal("expected", 1, "got", server.Requests("rewrap"))
+	}
+
+	// Rewrapped values are still decrypted to the original plain text.
+	err = server.SetMinDecryptionVersion("foo", 2)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	secret, err = vaultClient.Logical().Write("/transit/decrypt/foo", map[string]interface{}{"ciphertext": string(v2)})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if secret.Data["plaintext"] != "Zm9v" {
+		t.Fatal("expected", "Zm9v", "got", secret.Data["plaintext"])
+	}
+}
+
+func Test_Vault_Rewrap_Traverse_LatestVersion(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	var encryptTraverser *valuemodifier.Service
+	{
+		config := encrypt.DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		encryptService, err := encrypt.New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{encryptService}
+		encryptTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	var rewrapTraverser *valuemodifier.Service
+	{
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		rewrapService, err := New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+
+		c := valuemodifier.DefaultConfig()
+		c.ValueModifiers = []valuemodifier.ValueModifier{rewrapService}
+		rewrapTraverser, err = valuemodifier.New(c)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	encrypted, err := encryptTraverser.Traverse([]byte("k1: v1\nk2: v2\n"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Values already encrypted with the latest version are not rewrapped.
+	output, err := rewrapTraverser.Traverse(encrypted)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(output) != string(encrypted) {
+		t.Fatal("expected", fmt.Sprintf("%q", encrypted), "got", fmt.Sprintf("%q", output))
+	}
+	if server.Requests("rewrap") != 0 {
+		t.Fatal("expected", 0, "got", server.Requests("rewrap"))
+	}
+
+	_, err = server.RotateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	// Values are rewrapped to the latest version, which is read once per
+	// traversal.
+	first, err := rewrapTraverser.Traverse(encrypted)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if strings.Count(string(first), "vault:v2:") != 2 {
+		t.Fatal("expected", 2, "got", string(first))
+	}
+	if server.Requests("rewrap") != 2 {
+		t.Fatal("expected", 2, "got", server.Requests("rewrap"))
+	}
+	if server.Requests("keys") != 2 {
+		t.Fatal("expected", 2, "got", server.Requests("keys"))
+	}
+
+	// Rewrapping again does not change the output.
+	second, err := rewrapTraverser.Traverse(first)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(second) != string(first) {
+		t.Fatal("expected", fmt.Sprintf("%q", first), "got", fmt.Sprintf("%q", second))
+	}
+	if server.Requests("rewrap") != 2 {
+		t.Fatal("expected", 2, "got", server.Requests("rewrap"))
+	}
+}
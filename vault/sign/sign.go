@@ -0,0 +1,112 @@
+package sign
+
+import (
+	"encoding/base64"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+// Config represents the configuration used to create a new vault signing value
+// modifier.
+type Config struct {
+	VaultClient *vaultclient.Client
+	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// HashAlgorithm is the hash algorithm used to sign values, e.g. "sha2-256".
+	// When empty the Vault default is used.
+	HashAlgorithm string
+	// SignatureAlgorithm is the signature algorithm used by RSA keys, either
+	// "pss" or "pkcs1v15". When empty the Vault default is used.
+	SignatureAlgorithm string
+	// KeyVersion pins the version of the transit key used to sign values. When
+	// 0 the latest version is used.
+	KeyVersion int
+}
+
+// DefaultConfig provides a default configuration to create a new vault signing
+// value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		MountPath: "transit",
+	}
+}
+
+// New creates a new configured vault signing value modifier.
+func New(config Config) (*Service, error) {
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must be defined")
+	}
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
+	}
+	if config.KeyVersion < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.KeyVersion must not be negative")
+	}
+
+	path := transit.Path(config.MountPath, "sign", config.Key)
+	if config.HashAlgorithm != "" {
+		path += "/" + config.HashAlgorithm
+	}
+
+	newService := &Service{
+		vaultClient:        vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:               path,
+		signatureAlgorithm: config.SignatureAlgorithm,
+		keyVersion:         config.KeyVersion,
+	}
+
+	return newService, nil
+}
+
+// Service implements the vault signing value modifier.
+type Service struct {
+	vaultClient        *vaultapi.Client
+	path               string
+	signatureAlgorithm string
+	keyVersion         int
+}
+
+// Modify returns the given value prefixed with its signature, resulting in
+// "vault:v<version>:<signature>:<value>". The value is not encrypted. Use the
+// vault verification value modifier to verify the signature and unwrap the
+// value again. Empty values are returned as they are.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	data := map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(value),
+	}
+	if s.signatureAlgorithm != "" {
+		data["signature_algorithm"] = s.signatureAlgorithm
+	}
+	if s.keyVersion != 0 {
+		data["key_version"] = s.keyVersion
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	signature, err := vaultapi.String(secret, "signature")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	_, err = transit.KeyVersion(signature)
+	if err != nil {
+		return nil, microerror.Maskf(vaultResponseError, "Vault response field %q must be of the form vault:v<version>:<signature>", "signature")
+	}
+
+	return []byte(signature + ":" + string(value)), nil
+}
@@ -0,0 +1,95 @@
+package sign
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func Test_Vault_Sign_Service_Modify_Settings(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	_, err = server.RotateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	config.HashAlgorithm = "sha2-384"
+	config.SignatureAlgorithm = "pkcs1v15"
+	config.KeyVersion = 1
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	modified, err := newService.Modify([]byte("bar"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if !strings.HasPrefix(string(modified), "vault:v1:") || !strings.HasSuffix(string(modified), ":bar") {
+		t.Fatal("expected", "vault:v1:<signature>:bar", "got", string(modified))
+	}
+
+	request, ok := server.LastRequest("sign")
+	if !ok {
+		t.Fatal("expected", true, "got", ok)
+	}
+	if request.Path != "sign/foo/sha2-384" {
+		t.Fatal("expected", "sign/foo/sha2-384", "got", request.Path)
+	}
+	if request.Data["signature_algorithm"] != "pkcs1v15" {
+		t.Fatal("expected", "pkcs1v15", "got", request.Data["signature_algorithm"])
+	}
+	if request.Data["key_version"] != float64(1) {
+		t.Fatal("expected", 1, "got", request.Data["key_version"])
+	}
+}
+
+func Test_Vault_Sign_Service_Modify_Empty(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	config := DefaultConfig()
+	config.VaultClient = vaultClient
+	config.Key = "foo"
+	newService, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	modified, err := newService.Modify([]byte(""))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if string(modified) != "" {
+		t.Fatal("expected", "", "got", string(modified))
+	}
+	if server.Requests("sign") != 0 {
+		t.Fatal("expected", 0, "got", server.Requests("sign"))
+	}
+}
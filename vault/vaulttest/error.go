@@ -0,0 +1,30 @@
+package vaulttest
+
+import "github.com/giantswarm/microerror"
+
+var invalidVersionError = &microerror.Error{
+	Kind: "invalidVersionError",
+}
+
+// IsInvalidVersion asserts invalidVersionError.
+func IsInvalidVersion(err error) bool {
+	return microerror.Cause(err) == invalidVersionError
+}
+
+var keyExistsError = &microerror.Error{
+	Kind: "keyExistsError",
+}
+
+// IsKeyExists asserts keyExistsError.
+func IsKeyExists(err error) bool {
+	return microerror.Cause(err) == keyExistsError
+}
+
+var keyNotFoundError = &microerror.Error{
+	Kind: "keyNotFoundError",
+}
+
+// IsKeyNotFound asserts keyNotFoundError.
+func IsKeyNotFound(err error) bool {
+	return microerror.Cause(err) == keyNotFoundError
+}
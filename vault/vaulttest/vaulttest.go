@@ -0,0 +1,721 @@
+// Package vaulttest provides an in-memory stand-in for the Vault transit
+// secrets engine, so that value modifiers using Vault can be tested without a
+// real Vault.
+//
+// The stand-in emulates the encrypt, decrypt, rewrap, datakey, hmac, sign and
+// verify endpoints as well as reading keys of the transit secrets engine, including batch requests,
+// versioned keys and key derivation contexts. Cipher texts are real AES-GCM
+// cipher texts of the form "vault:v<version>:<base64 cipher text>", but they
+// are not compatible with cipher texts of a real Vault. Signatures are HMACs
+// over the input and the requested algorithms, so any key can be used for
+// signing.
+package vaulttest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+// Config represents the configuration used to create a new Vault transit
+// stand-in.
+type Config struct {
+	// Settings.
+
+	// MountPath is the path the emulated transit secrets engine is mounted at.
+	// It defaults to "transit".
+	MountPath string
+	// Token is the token every request must be authenticated with. Requests
+	// with any other token are denied. When empty any token is accepted.
+	Token string
+}
+
+// DefaultConfig provides a default configuration to create a new Vault transit
+// stand-in by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		MountPath: "transit",
+		Token:     "",
+	}
+}
+
+// New creates and starts a new Vault transit stand-in. Close must be called
+// once the stand-in is not needed anymore.
+func New(config Config) (*Server, error) {
+	mountPath := strings.Trim(config.MountPath, "/")
+	if mountPath == "" {
+		mountPath = "transit"
+	}
+
+	s := &Server{
+		keys:         map[string]*key{},
+		mountPath:    mountPath,
+		requests:     map[string]int{},
+		lastRequests: map[string]Request{},
+		token:        config.Token,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s, nil
+}
+
+// Server implements the Vault transit stand-in.
+type Server struct {
+	*httptest.Server
+
+	mutex        sync.Mutex
+	keys         map[string]*key
+	mountPath    string
+	requests     map[string]int
+	lastRequests map[string]Request
+	token        string
+}
+
+// Request is a request received by the stand-in.
+type Request struct {
+	// Path is the path of the request relative to the mount path, e.g.
+	// "encrypt/my-key".
+	Path string
+	// Namespace is the Vault Enterprise namespace the request was sent to. It
+	// is empty when no namespace was given.
+	Namespace string
+	// Data is the JSON body of the request.
+	Data map[string]interface{}
+}
+
+type key struct {
+	// Versions holds the key material of all versions, where the key of
+	// version N is found at index N-1.
+	Versions [][]byte
+	// MinDecryptionVersion is the minimum version cipher texts must be
+	// encrypted with to be decrypted or rewrapped.
+	MinDecryptionVersion int
+}
+
+// Client returns a new Vault client talking to the stand-in, authenticated
+// with the configured token.
+func (s *Server) Client() (*vaultclient.Client, error) {
+	config := vaultclient.DefaultConfig()
+	config.Address = s.URL
+	config.MaxRetries = 0
+
+	c, err := vaultclient.NewClient(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	token := s.token
+	if token == "" {
+		token = "vaulttest"
+	}
+	c.SetToken(token)
+
+	return c, nil
+}
+
+// CreateKey creates the transit key with the given name at version 1. Keys
+// are also created implicitly when encrypting with an unknown key, just like
+// Vault does.
+func (s *Server) CreateKey(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.keys[name]; ok {
+		return microerror.Maskf(keyExistsError, "key %q already exists", name)
+	}
+
+	_, err := s.createKey(name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// RotateKey adds a new version to the transit key with the given name and
+// returns it.
+func (s *Server) RotateKey(name string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	k, ok := s.keys[name]
+	if !ok {
+		return 0, microerror.Maskf(keyNotFoundError, "key %q does not exist", name)
+	}
+
+	b, err := newKeyMaterial()
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	k.Versions = append(k.Versions, b)
+
+	return len(k.Versions), nil
+}
+
+// SetMinDecryptionVersion sets the minimum version cipher texts must be
+// encrypted with to be decrypted or rewrapped using the transit key with the
+// given name.
+func (s *Server) SetMinDecryptionVersion(name string, version int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	k, ok := s.keys[name]
+	if !ok {
+		return microerror.Maskf(keyNotFoundError, "key %q does not exist", name)
+	}
+	if version < 1 || version > len(k.Versions) {
+		return microerror.Maskf(invalidVersionError, "version must be between 1 and %d", len(k.Versions))
+	}
+	k.MinDecryptionVersion = version
+
+	return nil
+}
+
+// Requests returns the number of requests sent to the given transit endpoint,
+// e.g. "encrypt", "decrypt", "rewrap", "datakey", "hmac", "sign", "verify" or
+// "keys".
+// Batch requests are counted once.
+func (s *Server) Requests(endpoint string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests[endpoint]
+}
+
+// LastRequest returns the last request sent to the given transit endpoint, so
+// that tests can assert the settings sent to Vault. It returns false when no
+// request was sent to the endpoint yet.
+func (s *Server) LastRequest(endpoint string) (Request, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.lastRequests[endpoint]
+	return r, ok
+}
+
+// request is a single, possibly batched, request item.
+type request struct {
+	Plaintext  string `json:"plaintext"`
+	Ciphertext string `json:"ciphertext"`
+	Context    string `json:"context"`
+	KeyVersion int    `json:"key_version"`
+	Bits       int    `json:"bits"`
+	Convergent bool   `json:"convergent_encryption"`
+
+	Input              string `json:"input"`
+	Signature          string `json:"signature"`
+	SignatureAlgorithm string `json:"signature_algorithm"`
+
+	// Algorithm is the hash algorithm given by the request path of the hmac,
+	// sign and verify endpoints.
+	Algorithm string `json:"-"`
+}
+
+type batchRequest struct {
+	request
+	BatchInput []request `json:"batch_input"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && r.Header.Get("X-Vault-Token") != s.token {
+		writeErrors(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	prefix := "/v1/" + s.mountPath + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeErrors(w, http.StatusNotFound, fmt.Sprintf("no handler for route %q", r.URL.Path))
+		return
+	}
+	relativePath := strings.TrimPrefix(r.URL.Path, prefix)
+	parts := strings.Split(relativePath, "/")
+
+	if r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "keys" {
+		s.readKey(w, relativePath, r.Header.Get("X-Vault-Namespace"), parts[1])
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		writeErrors(w, http.StatusMethodNotAllowed, "unsupported operation")
+		return
+	}
+
+	var endpoint, name, algorithm string
+	var handle func(k *key, req request) (map[string]interface{}, error)
+	switch {
+	case len(parts) == 2 && parts[0] == "encrypt":
+		endpoint, name, handle = parts[0], parts[1], s.encrypt
+	case len(parts) == 2 && parts[0] == "decrypt":
+		endpoint, name, handle = parts[0], parts[1], s.decrypt
+	case len(parts) == 2 && parts[0] == "rewrap":
+		endpoint, name, handle = parts[0], parts[1], s.rewrap
+	case len(parts) == 3 && parts[0] == "datakey" && (parts[1] == "plaintext" || parts[1] == "wrapped"):
+		plaintext := parts[1] == "plaintext"
+		endpoint, name = parts[0], parts[2]
+		handle = func(k *key, req request) (map[string]interface{}, error) {
+			return s.datakey(k, req, plaintext)
+		}
+	case (len(parts) == 2 || len(parts) == 3) && parts[0] == "hmac":
+		endpoint, name, handle = parts[0], parts[1], s.hmac
+	case (len(parts) == 2 || len(parts) == 3) && parts[0] == "sign":
+		endpoint, name, handle = parts[0], parts[1], s.sign
+	case (len(parts) == 2 || len(parts) == 3) && parts[0] == "verify":
+		endpoint, name, handle = parts[0], parts[1], s.verify
+	default:
+		writeErrors(w, http.StatusNotFound, fmt.Sprintf("no handler for route %q", r.URL.Path))
+		return
+	}
+
+	if len(parts) == 3 && endpoint != "datakey" {
+		algorithm = parts[2]
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, "failed to read request: "+err.Error())
+		return
+	}
+	var body batchRequest
+	var data map[string]interface{}
+	err = json.Unmarshal(b, &body)
+	if err == nil {
+		err = json.Unmarshal(b, &data)
+	}
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, "failed to parse JSON input: "+err.Error())
+		return
+	}
+	body.Algorithm = algorithm
+	for i := range body.BatchInput {
+		body.BatchInput[i].Algorithm = algorithm
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests[endpoint]++
+	s.lastRequests[endpoint] = Request{
+		Path:      relativePath,
+		Namespace: r.Header.Get("X-Vault-Namespace"),
+		Data:      data,
+	}
+
+	k, ok := s.keys[name]
+	if !ok {
+		if endpoint != "encrypt" {
+			writeErrors(w, http.StatusBadRequest, "encryption key not found")
+			return
+		}
+		k, err = s.createKey(name)
+		if err != nil {
+			writeErrors(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if len(body.BatchInput) == 0 || endpoint == "datakey" {
+		data, err := handle(k, body.request)
+		if err != nil {
+			writeErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeData(w, data)
+		return
+	}
+
+	var results []map[string]interface{}
+	for _, req := range body.BatchInput {
+		data, err := handle(k, req)
+		if err != nil {
+			data = map[string]interface{}{
+				"error": err.Error(),
+			}
+		}
+		results = append(results, data)
+	}
+	writeData(w, map[string]interface{}{
+		"batch_results": results,
+	})
+}
+
+// readKey emulates reading a transit key, returning its versions but no key
+// material.
+func (s *Server) readKey(w http.ResponseWriter, relativePath string, namespace string, name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests["keys"]++
+	s.lastRequests["keys"] = Request{
+		Path:      relativePath,
+		Namespace: namespace,
+	}
+
+	k, ok := s.keys[name]
+	if !ok {
+		// Vault answers reads of unknown keys without any data.
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	keys := map[string]interface{}{}
+	for i := range k.Versions {
+		keys[strconv.Itoa(i+1)] = 0
+	}
+	writeData(w, map[string]interface{}{
+		"name":                   name,
+		"type":                   "aes256-gcm96",
+		"keys":                   keys,
+		"latest_version":         len(k.Versions),
+		"min_decryption_version": k.MinDecryptionVersion,
+	})
+}
+
+func (s *Server) encrypt(k *key, req request) (map[string]interface{}, error) {
+	plaintext, err := base64.StdEncoding.DecodeString(req.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode plaintext")
+	}
+
+	ciphertext, err := seal(k, req.KeyVersion, plaintext, req.Context, req.Convergent)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"ciphertext":  ciphertext,
+		"key_version": versionOf(k, req.KeyVersion),
+	}
+
+	return data, nil
+}
+
+func (s *Server) decrypt(k *key, req request) (map[string]interface{}, error) {
+	plaintext, err := open(k, req.Ciphertext, req.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	}
+
+	return data, nil
+}
+
+func (s *Server) rewrap(k *key, req request) (map[string]interface{}, error) {
+	plaintext, err := open(k, req.Ciphertext, req.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(k, req.KeyVersion, plaintext, req.Context, false)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"ciphertext":  ciphertext,
+		"key_version": versionOf(k, req.KeyVersion),
+	}
+
+	return data, nil
+}
+
+func (s *Server) datakey(k *key, req request, plaintext bool) (map[string]interface{}, error) {
+	bits := req.Bits
+	if bits == 0 {
+		bits = 256
+	}
+	if bits != 128 && bits != 256 && bits != 512 {
+		return nil, fmt.Errorf("invalid bit length")
+	}
+
+	dataKey := make([]byte, bits/8)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(k, 0, dataKey, req.Context, false)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"ciphertext":  ciphertext,
+		"key_version": len(k.Versions),
+	}
+	if plaintext {
+		data["plaintext"] = base64.StdEncoding.EncodeToString(dataKey)
+	}
+
+	return data, nil
+}
+
+func (s *Server) hmac(k *key, req request) (map[string]interface{}, error) {
+	input, err := base64.StdEncoding.DecodeString(req.Input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode input as base64")
+	}
+
+	version := versionOf(k, req.KeyVersion)
+	if version < 1 || version > len(k.Versions) {
+		return nil, fmt.Errorf("invalid key version")
+	}
+
+	sum, err := mac(k.Versions[version-1], req.Algorithm, input)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"hmac": fmt.Sprintf("vault:v%d:%s", version, base64.StdEncoding.EncodeToString(sum)),
+	}
+
+	return data, nil
+}
+
+func (s *Server) sign(k *key, req request) (map[string]interface{}, error) {
+	input, err := base64.StdEncoding.DecodeString(req.Input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode input as base64")
+	}
+
+	version := versionOf(k, req.KeyVersion)
+	if version < 1 || version > len(k.Versions) {
+		return nil, fmt.Errorf("invalid key version")
+	}
+
+	sig, err := signature(k.Versions[version-1], req.Algorithm, req.SignatureAlgorithm, input)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"signature":   fmt.Sprintf("vault:v%d:%s", version, base64.StdEncoding.EncodeToString(sig)),
+		"key_version": version,
+	}
+
+	return data, nil
+}
+
+func (s *Server) verify(k *key, req request) (map[string]interface{}, error) {
+	input, err := base64.StdEncoding.DecodeString(req.Input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode input as base64")
+	}
+
+	parts := strings.SplitN(req.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return nil, fmt.Errorf("invalid signature: no prefix")
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+	if err != nil || version < 1 {
+		return nil, fmt.Errorf("invalid signature: version number could not be decoded")
+	}
+	if version > len(k.Versions) {
+		return nil, fmt.Errorf("invalid signature: version is too new")
+	}
+	if version < k.MinDecryptionVersion {
+		return nil, fmt.Errorf("ciphertext or signature version is disallowed by policy (too old)")
+	}
+	given, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: could not decode base64")
+	}
+
+	expected, err := signature(k.Versions[version-1], req.Algorithm, req.SignatureAlgorithm, input)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"valid": hmac.Equal(given, expected),
+	}
+
+	return data, nil
+}
+
+func (s *Server) createKey(name string) (*key, error) {
+	b, err := newKeyMaterial()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	k := &key{
+		Versions:             [][]byte{b},
+		MinDecryptionVersion: 1,
+	}
+	s.keys[name] = k
+
+	return k, nil
+}
+
+func newKeyMaterial() ([]byte, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+func versionOf(k *key, version int) int {
+	if version == 0 {
+		return len(k.Versions)
+	}
+
+	return version
+}
+
+// seal encrypts the given plain text using the given key version. The context
+// is authenticated, so that cipher texts can only be decrypted using the same
+// context, similar to derived keys of Vault.
+func seal(k *key, version int, plaintext []byte, context string, convergent bool) (string, error) {
+	version = versionOf(k, version)
+	if version < 1 || version > len(k.Versions) {
+		return "", fmt.Errorf("requested version for encryption is higher than the latest key version")
+	}
+	if convergent && context == "" {
+		return "", fmt.Errorf("missing 'context' for key derivation; the key was created using a derived key, which means additional, per-request information must be included in order to perform operations with the key")
+	}
+
+	aead, err := newAEAD(k.Versions[version-1])
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if convergent {
+		mac := hmac.New(sha256.New, k.Versions[version-1])
+		mac.Write([]byte(context))
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else {
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(context))
+
+	return fmt.Sprintf("vault:v%d:%s", version, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+func open(k *key, ciphertext string, context string) ([]byte, error) {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return nil, fmt.Errorf("invalid ciphertext: no prefix")
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+	if err != nil || version < 1 {
+		return nil, fmt.Errorf("invalid ciphertext: version number could not be decoded")
+	}
+	if version > len(k.Versions) {
+		return nil, fmt.Errorf("invalid ciphertext: version is too new")
+	}
+	if version < k.MinDecryptionVersion {
+		return nil, fmt.Errorf("ciphertext or signature version is disallowed by policy (too old)")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: could not decode base64")
+	}
+
+	aead, err := newAEAD(k.Versions[version-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid ciphertext: unable to decrypt")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(context))
+	if err != nil {
+		return nil, fmt.Errorf("cipher: message authentication failed")
+	}
+
+	return plaintext, nil
+}
+
+// mac computes the HMAC of the given input using the given hash algorithm. An
+// empty algorithm falls back to "sha2-256" like Vault does.
+func mac(key []byte, algorithm string, input []byte) ([]byte, error) {
+	var h func() hash.Hash
+	switch algorithm {
+	case "", "sha2-256":
+		h = sha256.New
+	case "sha2-224":
+		h = sha256.New224
+	case "sha2-384":
+		h = sha512.New384
+	case "sha2-512":
+		h = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+
+	m := hmac.New(h, key)
+	m.Write(input)
+
+	return m.Sum(nil), nil
+}
+
+// signature emulates a signature by computing an HMAC over the input and the
+// requested algorithms, so that verifying fails whenever any of them differ.
+func signature(key []byte, hashAlgorithm string, signatureAlgorithm string, input []byte) ([]byte, error) {
+	if hashAlgorithm == "" {
+		hashAlgorithm = "sha2-256"
+	}
+	switch signatureAlgorithm {
+	case "", "pss", "pkcs1v15":
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %s", signatureAlgorithm)
+	}
+
+	prefix := []byte(hashAlgorithm + ":" + signatureAlgorithm + ":")
+	return mac(key, hashAlgorithm, append(prefix, input...))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func writeData(w http.ResponseWriter, data map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": data,
+	})
+}
+
+func writeErrors(w http.ResponseWriter, statusCode int, errors ...string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": errors,
+	})
+}
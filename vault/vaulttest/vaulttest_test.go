@@ -0,0 +1,175 @@
+package vaulttest
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func Test_VaultTest_Server_Batch(t *testing.T) {
+	s, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer s.Close()
+
+	c, err := s.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	inputs := []string{"foo", "bar", ""}
+
+	var batch []map[string]interface{}
+	for _, input := range inputs {
+		batch = append(batch, map[string]interface{}{
+			"plaintext": base64.StdEncoding.EncodeToString([]byte(input)),
+		})
+	}
+	secret, err := c.Logical().Write("/transit/encrypt/foo", map[string]interface{}{"batch_input": batch})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	results := secret.Data["batch_results"].([]interface{})
+	if len(results) != len(inputs) {
+		t.Fatal("expected", len(inputs), "got", len(results))
+	}
+
+	batch = nil
+	for _, r := range results {
+		cipherText := r.(map[string]interface{})["ciphertext"].(string)
+		if !strings.HasPrefix(cipherText, "vault:v1:") {
+			t.Fatal("expected", "vault:v1:", "got", cipherText)
+		}
+		batch = append(batch, map[string]interface{}{
+			"ciphertext": cipherText,
+		})
+	}
+	batch = append(batch, map[string]interface{}{
+		"ciphertext": "foo",
+	})
+	secret, err = c.Logical().Write("/transit/decrypt/foo", map[string]interface{}{"batch_input": batch})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	results = secret.Data["batch_results"].([]interface{})
+	if len(results) != len(inputs)+1 {
+		t.Fatal("expected", len(inputs)+1, "got", len(results))
+	}
+
+	for i, input := range inputs {
+		plainText, err := base64.StdEncoding.DecodeString(results[i].(map[string]interface{})["plaintext"].(string))
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(plainText) != input {
+			t.Fatal("test", i+1, "expected", input, "got", string(plainText))
+		}
+	}
+	if _, ok := results[len(inputs)].(map[string]interface{})["error"]; !ok {
+		t.Fatal("expected", "error", "got", results[len(inputs)])
+	}
+
+	if s.Requests("encrypt") != 1 {
+		t.Fatal("expected", 1, "got", s.Requests("encrypt"))
+	}
+	if s.Requests("decrypt") != 1 {
+		t.Fatal("expected", 1, "got", s.Requests("decrypt"))
+	}
+}
+
+func Test_VaultTest_Server_Versions(t *testing.T) {
+	s, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer s.Close()
+
+	c, err := s.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	err = s.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	err = s.CreateKey("foo")
+	if !IsKeyExists(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+	_, err = s.RotateKey("bar")
+	if !IsKeyNotFound(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+
+	secret, err := c.Logical().Write("/transit/encrypt/foo", map[string]interface{}{"plaintext": "Zm9v"})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	v1 := secret.Data["ciphertext"].(string)
+
+	version, err := s.RotateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if version != 2 {
+		t.Fatal("expected", 2, "got", version)
+	}
+
+	secret, err = c.Logical().Write("/transit/rewrap/foo", map[string]interface{}{"ciphertext": v1})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	v2 := secret.Data["ciphertext"].(string)
+	if !strings.HasPrefix(v2, "vault:v2:") {
+		t.Fatal("expected", "vault:v2:", "got", v2)
+	}
+
+	err = s.SetMinDecryptionVersion("foo", 3)
+	if !IsInvalidVersion(err) {
+		t.Fatal("expected", true, "got", err)
+	}
+	err = s.SetMinDecryptionVersion("foo", 2)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	_, err = c.Logical().Write("/transit/decrypt/foo", map[string]interface{}{"ciphertext": v1})
+	if err == nil {
+		t.Fatal("expected", "error", "got", nil)
+	}
+	secret, err = c.Logical().Write("/transit/decrypt/foo", map[string]interface{}{"ciphertext": v2})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if secret.Data["plaintext"] != "Zm9v" {
+		t.Fatal("expected", "Zm9v", "got", secret.Data["plaintext"])
+	}
+}
+
+func Test_VaultTest_Server_Token(t *testing.T) {
+	config := DefaultConfig()
+	config.Token = "secret"
+	s, err := New(config)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer s.Close()
+
+	c, err := s.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	_, err = c.Logical().Write("/transit/encrypt/foo", map[string]interface{}{"plaintext": "Zm9v"})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	c.SetToken("wrong")
+	_, err = c.Logical().Write("/transit/encrypt/foo", map[string]interface{}{"plaintext": "Zm9v"})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatal("expected", "permission denied", "got", err)
+	}
+}
@@ -0,0 +1,111 @@
+package verify
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/internal/transit"
+	"github.com/giantswarm/valuemodifier/vault/internal/vaultapi"
+)
+
+// Config represents the configuration used to create a new vault verification
+// value modifier.
+type Config struct {
+	VaultClient *vaultclient.Client
+	Key         string
+	// MountPath is the path the transit secrets engine is mounted at. It
+	// defaults to "transit".
+	MountPath string
+	// Namespace is the Vault Enterprise namespace the transit secrets engine
+	// lives in. When empty the namespace of VaultClient is used.
+	Namespace string
+	// HashAlgorithm is the hash algorithm values were signed with, e.g.
+	// "sha2-256". When empty the Vault default is used.
+	HashAlgorithm string
+	// SignatureAlgorithm is the signature algorithm RSA keys signed values
+	// with, either "pss" or "pkcs1v15". When empty the Vault default is used.
+	SignatureAlgorithm string
+}
+
+// DefaultConfig provides a default configuration to create a new vault
+// verification value modifier by best effort.
+func DefaultConfig() Config {
+	return Config{
+		MountPath: "transit",
+	}
+}
+
+// New creates a new configured vault verification value modifier.
+func New(config Config) (*Service, error) {
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must be defined")
+	}
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Key must be defined")
+	}
+
+	path := transit.Path(config.MountPath, "verify", config.Key)
+	if config.HashAlgorithm != "" {
+		path += "/" + config.HashAlgorithm
+	}
+
+	newService := &Service{
+		vaultClient:        vaultapi.NewClient(config.VaultClient, config.Namespace),
+		path:               path,
+		signatureAlgorithm: config.SignatureAlgorithm,
+	}
+
+	return newService, nil
+}
+
+// Service implements the vault verification value modifier.
+type Service struct {
+	vaultClient        *vaultapi.Client
+	path               string
+	signatureAlgorithm string
+}
+
+// Modify verifies values of the form "vault:v<version>:<signature>:<value>"
+// as produced by the vault signing value modifier and returns the unwrapped
+// value. Empty values are returned as they are.
+func (s *Service) Modify(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	parts := strings.SplitN(string(value), ":", 4)
+	if len(parts) != 4 {
+		return nil, microerror.Maskf(invalidSignatureError, "signed value must be of the form vault:v<version>:<signature>:<value>")
+	}
+	signature := strings.Join(parts[:3], ":")
+	_, err := transit.KeyVersion(signature)
+	if err != nil {
+		return nil, microerror.Maskf(invalidSignatureError, "signed value must be of the form vault:v<version>:<signature>:<value>")
+	}
+
+	data := map[string]interface{}{
+		"input":     base64.StdEncoding.EncodeToString([]byte(parts[3])),
+		"signature": signature,
+	}
+	if s.signatureAlgorithm != "" {
+		data["signature_algorithm"] = s.signatureAlgorithm
+	}
+
+	secret, err := s.vaultClient.Write(s.path, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	valid, err := vaultapi.Bool(secret, "valid")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if !valid {
+		return nil, microerror.Maskf(invalidSignatureError, "signature must be valid")
+	}
+
+	return []byte(parts[3]), nil
+}
@@ -0,0 +1,159 @@
+package verify
+
+import (
+	"strings"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/valuemodifier/vault/sign"
+	"github.com/giantswarm/valuemodifier/vault/vaulttest"
+)
+
+func Test_Vault_Verify_Service_Modify_Malformed(t *testing.T) {
+	testCases := []struct {
+		Value string
+	}{
+		// Test case 1, values without signature are rejected.
+		{
+			Value: "foo",
+		},
+		// Test case 2, values with a malformed signature are rejected.
+		{
+			Value: "vault:vx:c2ln:foo",
+		},
+		// Test case 3, values missing the signed value are rejected.
+		{
+			Value: "vault:v1:c2ln",
+		},
+	}
+
+	// Vault is never called in the test cases below, so the client points to
+	// an address nothing listens on.
+	vaultClient, err := vaultclient.NewClient(&vaultclient.Config{Address: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		_, err = newService.Modify([]byte(tc.Value))
+		if !IsInvalidSignature(err) {
+			t.Fatal("test", i+1, "expected", true, "got", err)
+		}
+	}
+}
+
+func Test_Vault_Verify_Service_Modify(t *testing.T) {
+	server, err := vaulttest.New(vaulttest.DefaultConfig())
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	defer server.Close()
+
+	err = server.CreateKey("foo")
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	vaultClient, err := server.Client()
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	var signService *sign.Service
+	{
+		config := sign.DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.HashAlgorithm = "sha2-512"
+		signService, err = sign.New(config)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	signed, err := signService.Modify([]byte("bar:baz"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	other, err := signService.Modify([]byte("qux"))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	signature := strings.TrimSuffix(string(signed), ":bar:baz")
+	otherSignature := strings.TrimSuffix(string(other), ":qux")
+
+	testCases := []struct {
+		HashAlgorithm string
+		Value         string
+		Expected      string
+		ErrorMatcher  func(error) bool
+	}{
+		// Test case 1, signed values are verified and unwrapped.
+		{
+			HashAlgorithm: "sha2-512",
+			Value:         string(signed),
+			Expected:      "bar:baz",
+		},
+		// Test case 2, tampered values are rejected.
+		{
+			HashAlgorithm: "sha2-512",
+			Value:         signature + ":bar:bar",
+			ErrorMatcher:  IsInvalidSignature,
+		},
+		// Test case 3, tampered signatures are rejected.
+		{
+			HashAlgorithm: "sha2-512",
+			Value:         otherSignature + ":bar:baz",
+			ErrorMatcher:  IsInvalidSignature,
+		},
+		// Test case 4, signatures are rejected when verified using another
+		// hash algorithm than they were signed with.
+		{
+			HashAlgorithm: "sha2-256",
+			Value:         string(signed),
+			ErrorMatcher:  IsInvalidSignature,
+		},
+	}
+
+	for i, tc := range testCases {
+		config := DefaultConfig()
+		config.VaultClient = vaultClient
+		config.Key = "foo"
+		config.HashAlgorithm = tc.HashAlgorithm
+		newService, err := New(config)
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+
+		modified, err := newService.Modify([]byte(tc.Value))
+		if tc.ErrorMatcher != nil {
+			if !tc.ErrorMatcher(err) {
+				t.Fatal("test", i+1, "expected", true, "got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("test", i+1, "expected", nil, "got", err)
+		}
+		if string(modified) != tc.Expected {
+			t.Fatal("test", i+1, "expected", tc.Expected, "got", string(modified))
+		}
+
+		request, ok := server.LastRequest("verify")
+		if !ok {
+			t.Fatal("test", i+1, "expected", true, "got", ok)
+		}
+		if request.Path != "verify/foo/"+tc.HashAlgorithm {
+			t.Fatal("test", i+1, "expected", "verify/foo/"+tc.HashAlgorithm, "got", request.Path)
+		}
+	}
+}